@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
 	"github.com/englandrecoil/go-marketplace-service/internal/config"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
 	"github.com/gin-gonic/gin"
 
 	_ "github.com/englandrecoil/go-marketplace-service/docs"
@@ -23,14 +32,99 @@ func main() {
 	apiCfg := config.Init()
 	defer apiCfg.Conn.Close()
 
+	// SIGINT/SIGTERM stop the workers and let requests in flight finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	for _, run := range []func(context.Context){
+		apiCfg.RunImageChecker,
+		apiCfg.RunAccountPurger,
+		apiCfg.RunDataExporter,
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+
+	// reset tokens requested before the shutdown are still sent, so the sender stops after the server
+	senderCtx, stopSender := context.WithCancel(context.Background())
+	defer stopSender()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		apiCfg.RunPasswordResetSender(senderCtx)
+	}()
+
 	router := gin.Default()
+	if err := router.SetTrustedProxies(apiCfg.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	requireAuth := apiCfg.Auth.RequireAuth()
+	optionalAuth := apiCfg.Auth.OptionalAuth()
+
 	router.POST("/api/reg", apiCfg.HandlerRegister)
 	router.POST("/api/auth", apiCfg.HandlerAuth)
-	router.POST("/api/ads", apiCfg.HandlerCreateAd)
+	router.POST("/api/auth/refresh", apiCfg.HandlerRefreshToken)
+	router.POST("/api/auth/mfa", apiCfg.HandlerVerifyMFA)
+	router.POST("/api/auth/logout", requireAuth, apiCfg.HandlerLogout)
+	router.POST("/api/auth/password-reset", apiCfg.HandlerRequestPasswordReset)
+	router.POST("/api/auth/password-reset/confirm", apiCfg.HandlerConfirmPasswordReset)
+	router.PATCH("/api/users/me", requireAuth, apiCfg.HandlerUpdateMyProfile)
+	router.DELETE("/api/users/me", requireAuth, apiCfg.HandlerDeleteAccount)
+	router.PUT("/api/users/me/password", requireAuth, apiCfg.HandlerChangePassword)
+	router.POST("/api/users/me/2fa/totp", requireAuth, apiCfg.HandlerEnrollTOTP)
+	router.POST("/api/users/me/2fa/totp/confirm", requireAuth, apiCfg.HandlerConfirmTOTP)
+	router.DELETE("/api/users/me/2fa/totp", requireAuth, apiCfg.HandlerDisableTOTP)
+	router.POST("/api/users/me/exports", requireAuth, apiCfg.HandlerCreateDataExport)
+	router.GET("/api/users/me/exports/:id", requireAuth, apiCfg.HandlerGetDataExport)
+	router.POST("/api/ads", requireAuth, apiCfg.HandlerCreateAd)
+	router.PATCH("/api/ads/:id", requireAuth, apiCfg.HandlerUpdateAd)
+	router.DELETE("/api/ads/:id", requireAuth, apiCfg.HandlerDeleteAd)
+	router.POST("/api/ads/:id/status", requireAuth, apiCfg.HandlerUpdateAdStatus)
+	router.PUT("/api/ads/:id/images", requireAuth, apiCfg.HandlerUpdateAdImages)
+	router.POST("/api/images", requireAuth, apiCfg.HandlerUploadImage)
+	router.PUT("/api/admin/users/:login/role", requireAuth, auth.RequireRole(auth.RoleAdmin), apiCfg.HandlerUpdateUserRole)
 
-	router.GET("/api/ads", apiCfg.HandlerGetAds)
+	router.GET("/api/ads", optionalAuth, apiCfg.HandlerGetAds)
+	router.GET("/api/ads/:id", optionalAuth, apiCfg.HandlerGetAdByID)
+	router.GET("/api/categories", apiCfg.HandlerGetCategories)
+	router.GET("/api/users/me", requireAuth, apiCfg.HandlerGetMyProfile)
+	router.GET("/api/users/:login", optionalAuth, apiCfg.HandlerGetUserProfile)
+	router.GET("/api/images/:id", apiCfg.HandlerGetImage)
+	router.GET("/api/images/:id/variants/:width", apiCfg.HandlerGetImageVariant)
+	router.GET("/api/images/proxy/:adID", apiCfg.HandlerGetProxiedImage)
+	router.GET("/api/exports/:id/download", apiCfg.HandlerDownloadDataExport)
+	router.GET("/.well-known/jwks.json", apiCfg.HandlerGetJWKS)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	log.Fatal(router.Run())
+	// the same address router.Run() would listen on
+	addr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	server := &http.Server{Addr: addr, Handler: router}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+	log.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("couldn't shut down the server: %v", err)
+	}
+	stopSender()
+	workers.Wait()
 }
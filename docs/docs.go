@@ -15,9 +15,113 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Возвращает JSON Web Key Set (RFC 7517) с публичными ключами, которыми можно проверить подпись JWT. Поле ` + "`" + `kid` + "`" + ` в заголовке токена указывает, каким ключом он подписан. При подписи HS256 список ключей пуст.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Получить публичные ключи для проверки токенов",
+                "responses": {
+                    "200": {
+                        "description": "Набор ключей",
+                        "schema": {
+                            "$ref": "#/definitions/dto.JWKSResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/users/{login}/role": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Назначает пользователю роль ` + "`" + `user` + "`" + `, ` + "`" + `moderator` + "`" + ` или ` + "`" + `admin` + "`" + `. Доступно только администраторам. Выданные пользователю JWT отзываются, новая роль попадает в токен при следующем обновлении через ` + "`" + `/api/auth/refresh` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Изменить роль пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Логин пользователя",
+                        "name": "login",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новая роль",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateUserRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Роль изменена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserRoleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Нельзя изменить собственную роль",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/ads": {
             "get": {
-                "description": "Позволяет получить объявления пользователей. Авторизованным пользователям доступно получение параметра ` + "`" + `is_owner` + "`" + `.",
+                "description": "Позволяет получить опубликованные объявления пользователей. Авторизованным пользователям доступно получение параметра ` + "`" + `is_owner` + "`" + `, а также своих объявлений в любом статусе. В ` + "`" + `image_address` + "`" + ` возвращается обложка объявления, для загруженных изображений в ` + "`" + `image_variants` + "`" + ` также возвращаются ссылки на уменьшенные копии.",
                 "produces": [
                     "application/json"
                 ],
@@ -30,6 +134,12 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header"
                     },
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос по заголовку и описанию",
+                        "name": "q",
+                        "in": "query"
+                    },
                     {
                         "minimum": 1,
                         "type": "integer",
@@ -38,6 +148,12 @@ const docTemplate = `{
                         "name": "page",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Курсор next_cursor из предыдущего ответа, при его наличии page игнорируется. Курсор действует только с теми же фильтрами и сортировкой, с которыми был получен",
+                        "name": "cursor",
+                        "in": "query"
+                    },
                     {
                         "maximum": 100,
                         "minimum": 1,
@@ -61,14 +177,21 @@ const docTemplate = `{
                         "name": "max_price",
                         "in": "query"
                     },
+                    {
+                        "type": "integer",
+                        "description": "ID категории, включая все её подкатегории",
+                        "name": "category",
+                        "in": "query"
+                    },
                     {
                         "enum": [
                             "price",
-                            "created_at"
+                            "created_at",
+                            "relevance"
                         ],
                         "type": "string",
                         "default": "created_at",
-                        "description": "Поле для сортировки",
+                        "description": "Поле для сортировки, relevance доступно только вместе с q",
                         "name": "sort_by",
                         "in": "query"
                     },
@@ -82,15 +205,49 @@ const docTemplate = `{
                         "description": "Направление сортировки",
                         "name": "order",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "published",
+                            "reserved",
+                            "sold",
+                            "archived"
+                        ],
+                        "type": "string",
+                        "default": "published",
+                        "description": "Статус объявлений, отличный от published доступен только для своих объявлений",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только свои объявления",
+                        "name": "mine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Подсчитывать общее количество объявлений, false позволяет сэкономить на запросе",
+                        "name": "with_total",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "Успешный ответ",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.GetAdsResponse"
+                            "$ref": "#/definitions/dto.GetAdsListResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на соседние страницы (RFC 8288)"
+                            },
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Курсор следующей страницы, совпадает с next_cursor"
                             }
                         }
                     },
@@ -120,7 +277,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Создаёт новое объявление с заданными параметрами",
+                "description": "Создаёт новое объявление с заданными параметрами. Объявление может содержать до 10 изображений (` + "`" + `images` + "`" + `), каждое задаётся либо ссылкой ` + "`" + `image_address` + "`" + `, либо ` + "`" + `image_id` + "`" + ` ранее загруженного изображения. Первое изображение становится обложкой. Поля ` + "`" + `image_address` + "`" + `/` + "`" + `image_id` + "`" + ` верхнего уровня поддерживаются для совместимости и добавляют изображение в начало галереи. Новое объявление может быть черновиком (` + "`" + `draft` + "`" + `) или сразу опубликованным (` + "`" + `published` + "`" + `, по умолчанию).",
                 "consumes": [
                     "application/json"
                 ],
@@ -175,42 +332,104 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/auth": {
-            "post": {
-                "description": "Аутентифицирует пользователя по заданному логину и паролю и возвращает JWT",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/ads/{id}": {
+            "get": {
+                "description": "Возвращает объявление целиком по его ID вместе с галереей изображений. Неопубликованные объявления доступны только их автору. Авторизованным пользователям доступно получение параметра ` + "`" + `is_owner` + "`" + `.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Аутентифицировать пользователя",
+                "summary": "Получить объявление",
                 "parameters": [
                     {
-                        "description": "Данные пользователя для входа",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.CredentialsRequest"
-                        }
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешная аутентификация",
+                        "description": "Успешный ответ",
                         "schema": {
-                            "$ref": "#/definitions/dto.AuthResponse"
+                            "$ref": "#/definitions/dto.GetAdResponse"
                         }
                     },
-                    "400": {
-                        "description": "Неверный формат запроса",
+                    "401": {
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
                         "schema": {
                             "$ref": "#/definitions/dto.ErrorResponse"
                         }
                     },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет объявление. Доступно только автору объявления.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Удалить объявление",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Объявление удалено"
+                    },
                     "401": {
-                        "description": "Неверный логин или пароль",
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Объявление принадлежит другому пользователю",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
                         "schema": {
                             "$ref": "#/definitions/dto.ErrorResponse"
                         }
@@ -222,38 +441,81 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/reg": {
-            "post": {
-                "description": "Создаёт нового пользователя с заданным логином и паролем",
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Частично обновляет объявление. Доступно только автору объявления, переданные поля проходят ту же валидацию, что и при создании. Новое изображение заменяет обложку объявления, остальная галерея изменяется через ` + "`" + `PUT /api/ads/{id}/images` + "`" + `.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Зарегистрировать нового пользователя",
+                "summary": "Изменить объявление",
                 "parameters": [
                     {
-                        "description": "Данные пользователя для входа",
-                        "name": "credentials",
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменяемые параметры объявления",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.CredentialsRequest"
+                            "$ref": "#/definitions/dto.UpdateAdsRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Успешная регистрация",
+                    "200": {
+                        "description": "Объявление обновлено",
                         "schema": {
-                            "$ref": "#/definitions/dto.RegisterResponse"
+                            "$ref": "#/definitions/dto.GetAdResponse"
                         }
                     },
                     "400": {
-                        "description": "Неверный формат запроса, логин уже используется или пароль слишком слабый",
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Объявление принадлежит другому пользователю",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Объявление изменено параллельным запросом",
                         "schema": {
                             "$ref": "#/definitions/dto.ErrorResponse"
                         }
@@ -266,43 +528,1900 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.AuthResponse": {
-            "type": "object",
-            "properties": {
-                "token": {
-                    "type": "string"
-                }
-            }
         },
-        "dto.CreateAdsRequest": {
-            "type": "object",
-            "required": [
-                "description",
-                "image_address",
-                "price",
-                "title"
-            ],
-            "properties": {
+        "/api/ads/{id}/images": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Заменяет галерею объявления целиком: порядок изображений в запросе становится их порядком в объявлении, обложка выбирается по индексу ` + "`" + `cover_index` + "`" + `. Чтобы переставить изображения, достаточно передать уже существующие в новом порядке. Доступно только автору объявления, не более 10 изображений.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Изменить галерею объявления",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изображения объявления",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateAdImagesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Галерея обновлена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetAdResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Объявление принадлежит другому пользователю",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ads/{id}/status": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Переводит объявление в новый статус. Допустимые переходы: draft → published/archived, published → draft/reserved/sold/archived, reserved → published/sold/archived, sold → archived, archived → draft/published.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Изменить статус объявления",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новый статус",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateAdStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Статус изменён",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetAdResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Объявление принадлежит другому пользователю",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Недопустимый переход статуса или статус изменён параллельным запросом",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth": {
+            "post": {
+                "description": "Аутентифицирует пользователя по заданному логину и паролю и возвращает JWT вместе с refresh-токеном. Если у пользователя включена двухфакторная аутентификация, вместо них возвращается ` + "`" + `mfa_token` + "`" + `, который вместе с кодом нужно передать в ` + "`" + `POST /api/auth/mfa` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Аутентифицировать пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя для входа",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CredentialsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Пароль верный, требуется второй фактор",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MFARequiredResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный логин или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток входа, время ожидания в заголовке Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает текущий JWT и, если передан, refresh-токен вместе со всей цепочкой выданных по нему токенов. С ` + "`" + `all: true` + "`" + ` отзываются все JWT и refresh-токены пользователя, выданные до этого момента, на всех устройствах. Тело запроса необязательно.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Выйти из аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры выхода",
+                        "name": "logout",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LogoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Выход выполнен"
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/mfa": {
+            "post": {
+                "description": "Обменивает ` + "`" + `mfa_token` + "`" + `, полученный в ` + "`" + `POST /api/auth` + "`" + `, и код из приложения-аутентификатора или код восстановления на JWT и refresh-токен. ` + "`" + `mfa_token` + "`" + ` действует 5 минут, принимает не больше 5 попыток и обменивается на токены только один раз, после этого нужно снова войти с паролем. Неверный код учитывается как неудачная попытка входа.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Завершить вход вторым фактором",
+                "parameters": [
+                    {
+                        "description": "Токен первого шага и код",
+                        "name": "mfa",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MFARequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Недействительный, исчерпанный или уже использованный mfa_token или неверный код",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток входа, время ожидания в заголовке Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/password-reset": {
+            "post": {
+                "description": "Отправляет пользователю одноразовый токен для сброса пароля, действующий 1 час. Ранее выданные токены сброса перестают действовать. Ответ не зависит от того, существует ли логин: токен готовится и отправляется уже после ответа. Первые 3 запроса для логина (10 для IP-адреса) проходят без задержки, после каждого следующего новые запросы отклоняются на время, которое удваивается: 1 минута, 2, 4 и так далее, но не больше часа.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Запросить сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Логин пользователя",
+                        "name": "reset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Запрос принят"
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов сброса, время ожидания в заголовке Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Очередь отправки токенов переполнена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/password-reset/confirm": {
+            "post": {
+                "description": "Устанавливает новый пароль по токену сброса. Токен можно использовать только один раз, все выданные ранее JWT и refresh-токены пользователя отзываются.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Сбросить пароль",
+                "parameters": [
+                    {
+                        "description": "Токен сброса и новый пароль",
+                        "name": "reset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConfirmPasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пароль изменён"
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса, новый пароль слишком слабый, токен недействителен или истёк",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/refresh": {
+            "post": {
+                "description": "Обменивает refresh-токен на новую пару из JWT и refresh-токена, старый refresh-токен после этого недействителен. Повторное предъявление уже использованного refresh-токена считается признаком кражи: все токены, выданные по цепочке от того же входа, отзываются.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Обновить токены",
+                "parameters": [
+                    {
+                        "description": "Refresh-токен",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новая пара токенов",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Refresh-токен недействителен, истёк или уже был использован",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/categories": {
+            "get": {
+                "description": "Возвращает дерево категорий объявлений",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Получить категории",
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.CategoryResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/exports/{id}/download": {
+            "get": {
+                "description": "Отдаёт ZIP-архив выгрузки. Ссылка с параметрами ` + "`" + `expires` + "`" + ` и ` + "`" + `signature` + "`" + ` берётся из ` + "`" + `download_url` + "`" + ` ответа о статусе выгрузки, токен не нужен.",
+                "produces": [
+                    "application/zip"
+                ],
+                "summary": "Скачать выгрузку данных",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID выгрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Время окончания действия ссылки (Unix time)",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подпись ссылки",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ZIP-архив",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Ссылка недействительна или истекла",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Выгрузка не найдена или уже удалена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/images": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Загружает изображение (по умолчанию jpeg/png, список форматов настраивается; не более 10 МБ и 6000×6000 пикселей) и возвращает его ID, который можно указать в объявлении. Формат определяется по содержимому файла, а не по заголовкам. Для изображения создаются уменьшенные копии шириной 200, 600 и 1200 пикселей (не шире оригинала) с учётом EXIF-ориентации и без метаданных: для jpeg и webp в формате jpeg, для png и gif в формате png. Из оригинала удаляются метаданные (EXIF, XMP, IPTC, комментарии), у jpeg сохраняется только ориентация.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Загрузить изображение",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Файл изображения",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Изображение загружено",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UploadImageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат или размер изображения",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/images/proxy/{adID}": {
+            "get": {
+                "description": "Отдаёт изображение опубликованного объявления с нашего домена. Внешнее изображение скачивается один раз и дальше отдаётся из кэша на диске, неудачная загрузка повторяется не раньше чем через минуту, поддерживаются заголовки ` + "`" + `ETag` + "`" + `/` + "`" + `If-None-Match` + "`" + ` и ` + "`" + `Last-Modified` + "`" + `/` + "`" + `If-Modified-Since` + "`" + `. Для загруженных изображений выполняется перенаправление на ` + "`" + `/api/images/{id}` + "`" + `.",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/gif",
+                    "image/webp"
+                ],
+                "summary": "Получить изображение объявления через прокси",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID объявления",
+                        "name": "adID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Изображение не изменилось",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено или его изображение недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Не удалось получить изображение с внешнего хоста",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/images/{id}": {
+            "get": {
+                "description": "Отдаёт загруженное изображение. Изображения удалённых аккаунтов не отдаются.",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/gif",
+                    "image/webp"
+                ],
+                "summary": "Получить изображение",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID изображения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Изображение не найдено или его владелец удалил аккаунт",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/images/{id}/variants/{width}": {
+            "get": {
+                "description": "Отдаёт уменьшенную копию загруженного изображения заданной ширины. Доступные ширины перечислены в ` + "`" + `variants` + "`" + ` ответа на загрузку и в ` + "`" + `image_variants` + "`" + ` ленты объявлений.",
+                "produces": [
+                    "image/jpeg",
+                    "image/png"
+                ],
+                "summary": "Получить уменьшенную копию изображения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID изображения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 600,
+                        "description": "Ширина копии",
+                        "name": "width",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Изображение не найдено или его владелец удалил аккаунт",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/reg": {
+            "post": {
+                "description": "Создаёт нового пользователя с заданным логином и паролем",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Зарегистрировать нового пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя для входа",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CredentialsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Успешная регистрация",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса, логин уже используется или пароль слишком слабый",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает профиль текущего пользователя вместе с контактами, даже если они скрыты от других",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Получить свой профиль",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Профиль пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserProfileResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет аккаунт текущего пользователя после подтверждения паролем, при включённой двухфакторной аутентификации нужен ещё и код. Логин освобождается, профиль и двухфакторная аутентификация удаляются, все объявления переносятся в архив, все токены отзываются. Сами данные окончательно удаляются фоновой задачей по истечении срока хранения.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Удалить аккаунт",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Пароль и, при включённой двухфакторной аутентификации, код",
+                        "name": "confirmation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Аккаунт удалён"
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса или не передан код",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный пароль или код",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь — единственный администратор",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, время ожидания в заголовке Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Изменяет переданные поля профиля, остальные остаются прежними. Пустая строка очищает поле. Аватар задаётся через ` + "`" + `avatar_image_id` + "`" + ` изображения, загруженного через ` + "`" + `POST /api/images` + "`" + `. ` + "`" + `contact_preferences` + "`" + ` заменяются целиком, другим пользователям они показываются только при ` + "`" + `public: true` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Обновить свой профиль",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Поля профиля",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Обновлённый профиль",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserProfileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса или недопустимые значения полей",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/me/2fa/totp": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт секрет TOTP (RFC 6238) и возвращает его вместе с otpauth:// URI для приложения-аутентификатора. Двухфакторная аутентификация включится только после подтверждения кодом из приложения. Повторный вызов до подтверждения заменяет секрет. Неверный пароль учитывается как неудачная попытка входа.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Начать подключение двухфакторной аутентификации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Текущий пароль",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.EnrollTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Секрет для приложения-аутентификатора",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TOTPEnrollmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный пароль",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Двухфакторная аутентификация уже включена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, время ожидания в заголовке Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отключает двухфакторную аутентификацию и удаляет коды восстановления. Нужны текущий пароль и код из приложения-аутентификатора или код восстановления.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Отключить двухфакторную аутентификацию",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Пароль и код",
+                        "name": "confirmation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DisableTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Двухфакторная аутентификация отключена"
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный пароль или код",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Двухфакторная аутентификация не включена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, время ожидания в заголовке Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/me/2fa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Включает двухфакторную аутентификацию, если код из приложения-аутентификатора верный, и возвращает одноразовые коды восстановления. Коды показываются только один раз, каждый из них можно использовать вместо кода из приложения.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Подтвердить подключение двухфакторной аутентификации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Код из приложения-аутентификатора",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConfirmTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Двухфакторная аутентификация включена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса или неверный код",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Подключение не начато или уже подтверждено",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/me/exports": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Запускает подготовку ZIP-архива со всеми данными, которые сервис хранит о текущем пользователе: профиль, объявления, загруженные изображения, сессии и события безопасности. Архив собирается в фоне, его статус можно узнать по ссылке из заголовка Location. Одновременно готовится только одна выгрузка, повторный запрос возвращает уже начатую.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Запросить выгрузку данных",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Выгрузка поставлена в очередь",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DataExportResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес для проверки статуса выгрузки"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/me/exports/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает статус выгрузки. Когда архив готов, в ` + "`" + `download_url` + "`" + ` возвращается ссылка на скачивание, которая не требует токена и действует 15 минут; за новой ссылкой можно обратиться повторно. Архив хранится 24 часа после подготовки.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Получить статус выгрузки данных",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID выгрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Статус выгрузки",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DataExportResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Выгрузка не найдена или уже удалена",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/me/password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Меняет пароль текущего пользователя после проверки старого пароля. Все выданные ранее токены отзываются на всех устройствах, в ответе возвращается новая пара токенов для текущего клиента. Неверный старый пароль учитывается как неудачная попытка входа.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Сменить пароль",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Старый и новый пароли",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменён",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат запроса или новый пароль слишком слабый",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Отсутствует или недействителен токен доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный старый пароль",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, время ожидания в заголовке Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{login}": {
+            "get": {
+                "description": "Возвращает публичный профиль пользователя, количество его опубликованных объявлений и сами объявления с той же пагинацией, что и ` + "`" + `GET /api/ads` + "`" + `. Контакты возвращаются, только если пользователь сделал их публичными.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Получить страницу продавца",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "Bearer J2bc3Cd0F...",
+                        "description": "Bearer токен",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Логин пользователя",
+                        "name": "login",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор next_cursor из предыдущего ответа, при его наличии page игнорируется",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 25,
+                        "description": "Количество возвращаемых объявлений",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "price",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Поле для сортировки",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Направление сортировки",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница продавца",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PublicUserProfileResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на соседние страницы объявлений (RFC 8288)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Невалидный или просроченный токен-доступа",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.AdImageRequest": {
+            "type": "object",
+            "properties": {
+                "image_address": {
+                    "type": "string"
+                },
+                "image_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdImageResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "image_address": {
+                    "type": "string"
+                },
+                "image_id": {
+                    "type": "string"
+                },
+                "is_cover": {
+                    "type": "boolean"
+                },
+                "position": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CategoryResponse": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.CategoryResponse"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "old_password"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ConfirmPasswordResetRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "token"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ConfirmTOTPRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ContactPreferences": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "preferred": {
+                    "type": "string",
+                    "enum": [
+                        "chat",
+                        "phone",
+                        "email",
+                        "telegram"
+                    ]
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "telegram": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreateAdsRequest": {
+            "type": "object",
+            "required": [
+                "category_id",
+                "description",
+                "price",
+                "title"
+            ],
+            "properties": {
+                "category_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "image_address": {
+                    "type": "string"
+                },
+                "image_id": {
+                    "type": "string"
+                },
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AdImageRequest"
+                    }
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreateAdsResponse": {
+            "type": "object",
+            "properties": {
+                "category_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image_address": {
+                    "type": "string"
+                },
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AdImageResponse"
+                    }
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CredentialsRequest": {
+            "type": "object",
+            "required": [
+                "login",
+                "password"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DataExportResponse": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "download_url": {
+                    "type": "string"
+                },
+                "download_url_expires_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "processing",
+                        "ready",
+                        "failed"
+                    ]
+                }
+            }
+        },
+        "dto.DeleteAccountRequest": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DisableTOTPRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.EnrollTOTPRequest": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GetAdResponse": {
+            "type": "object",
+            "properties": {
+                "author_login": {
+                    "type": "string"
+                },
+                "category_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "string"
+                },
                 "image_address": {
                     "type": "string"
                 },
+                "image_broken": {
+                    "type": "boolean"
+                },
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AdImageResponse"
+                    }
+                },
+                "is_owner": {
+                    "type": "boolean"
+                },
                 "price": {
                     "type": "integer"
                 },
+                "status": {
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "dto.CreateAdsResponse": {
+        "dto.GetAdsListResponse": {
+            "type": "object",
+            "properties": {
+                "has_next": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GetAdsResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.GetAdsResponse": {
             "type": "object",
             "properties": {
+                "author_login": {
+                    "type": "string"
+                },
+                "category_id": {
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -315,42 +2434,243 @@ const docTemplate = `{
                 "image_address": {
                     "type": "string"
                 },
+                "image_variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ImageVariantResponse"
+                    }
+                },
+                "is_owner": {
+                    "type": "boolean"
+                },
                 "price": {
                     "type": "integer"
                 },
+                "status": {
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
                 }
             }
         },
-        "dto.CredentialsRequest": {
+        "dto.ImageVariantResponse": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.JWKResponse": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.JWKSResponse": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.JWKResponse"
+                    }
+                }
+            }
+        },
+        "dto.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "boolean"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MFARequest": {
             "type": "object",
             "required": [
-                "login",
-                "password"
+                "code",
+                "mfa_token"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "mfa_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MFARequiredResponse": {
+            "type": "object",
+            "properties": {
+                "mfa_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PasswordResetRequest": {
+            "type": "object",
+            "required": [
+                "login"
             ],
             "properties": {
                 "login": {
                     "type": "string"
+                }
+            }
+        },
+        "dto.PublicUserProfileResponse": {
+            "type": "object",
+            "properties": {
+                "active_ads_count": {
+                    "type": "integer"
+                },
+                "ads": {
+                    "$ref": "#/definitions/dto.GetAdsListResponse"
+                },
+                "avatar_url": {
+                    "type": "string"
                 },
-                "password": {
+                "avatar_variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ImageVariantResponse"
+                    }
+                },
+                "bio": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "contacts": {
+                    "$ref": "#/definitions/dto.ContactPreferences"
+                },
+                "display_name": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "registered_at": {
                     "type": "string"
                 }
             }
         },
-        "dto.ErrorResponse": {
+        "dto.RecoveryCodesResponse": {
             "type": "object",
             "properties": {
-                "error": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.RefreshTokenRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
                     "type": "string"
                 }
             }
         },
-        "dto.GetAdsResponse": {
+        "dto.RegisterResponse": {
             "type": "object",
             "properties": {
-                "author_login": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TOTPEnrollmentResponse": {
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
+                    "type": "string"
+                },
+                "secret": {
                     "type": "string"
+                }
+            }
+        },
+        "dto.UpdateAdImagesRequest": {
+            "type": "object",
+            "required": [
+                "images"
+            ],
+            "properties": {
+                "cover_index": {
+                    "type": "integer"
+                },
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AdImageRequest"
+                    }
+                }
+            }
+        },
+        "dto.UpdateAdStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UpdateAdsRequest": {
+            "type": "object",
+            "properties": {
+                "category_id": {
+                    "type": "integer"
                 },
                 "description": {
                     "type": "string"
@@ -358,8 +2678,8 @@ const docTemplate = `{
                 "image_address": {
                     "type": "string"
                 },
-                "is_owner": {
-                    "type": "boolean"
+                "image_id": {
+                    "type": "string"
                 },
                 "price": {
                     "type": "integer"
@@ -369,18 +2689,124 @@ const docTemplate = `{
                 }
             }
         },
-        "dto.RegisterResponse": {
+        "dto.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "avatar_image_id": {
+                    "type": "string"
+                },
+                "bio": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "contact_preferences": {
+                    "$ref": "#/definitions/dto.ContactPreferences"
+                },
+                "display_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UpdateUserRoleRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "moderator",
+                        "admin"
+                    ]
+                }
+            }
+        },
+        "dto.UploadImageResponse": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ImageVariantResponse"
+                    }
+                }
+            }
+        },
+        "dto.UserProfileResponse": {
             "type": "object",
             "properties": {
+                "avatar_image_id": {
+                    "type": "string"
+                },
+                "avatar_url": {
+                    "type": "string"
+                },
+                "avatar_variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ImageVariantResponse"
+                    }
+                },
+                "bio": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "contact_preferences": {
+                    "$ref": "#/definitions/dto.ContactPreferences"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "display_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UserRoleResponse": {
+            "type": "object",
+            "properties": {
                 "id": {
                     "type": "string"
                 },
                 "login": {
                     "type": "string"
                 },
+                "role": {
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
                 }
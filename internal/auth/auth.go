@@ -1,8 +1,13 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +24,8 @@ var (
 	ErrMissingAuthorizationHeader       = errors.New("missing authorization header")
 	ErrInvalidAuthorizationHeaderFormat = errors.New("invalid authorization header format")
 	ErrInvalidIssuer                    = errors.New("invalid issuer")
+	ErrTokenRevoked                     = errors.New("token has been revoked")
+	ErrMissingTokenID                   = errors.New("token has no id")
 )
 
 func HashPassword(password string) (string, error) {
@@ -33,26 +40,84 @@ func CheckPasswordHash(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// dummyHash is a hash no password matches, it's generated with the same cost as real ones.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// CheckPasswordDummy takes as long as CheckPasswordHash, it's called when there is no user
+// to check against, so response time doesn't reveal whether a login exists.
+func CheckPasswordDummy(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+}
+
+// Claims are the claims of our access tokens.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
+func MakeJWT(userID uuid.UUID, role string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	return MakeJWTAfter(userID, role, keys, expiresIn, time.Time{})
+}
+
+// MakeJWTAfter makes a token that isn't covered by the revocation of every token issued up to validAfter.
+// The issue time has second precision, so a token made in the same second is dated the next one.
+func MakeJWTAfter(userID uuid.UUID, role string, keys *KeySet, expiresIn time.Duration, validAfter time.Time) (string, error) {
 	currentTime := time.Now().UTC()
+	issuedAt := currentTime.Truncate(time.Second)
+	if !validAfter.IsZero() && !issuedAt.After(validAfter) {
+		issuedAt = validAfter.Truncate(time.Second).Add(time.Second)
+	}
 
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.RegisteredClaims{
-			Issuer:    string(TokenTypeAccess),
-			IssuedAt:  jwt.NewNumericDate(currentTime),
-			ExpiresAt: jwt.NewNumericDate(currentTime.Add(expiresIn)),
-			Subject:   userID.String(),
+	signedToken, err := keys.Sign(
+		Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    string(TokenTypeAccess),
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(currentTime.Add(expiresIn)),
+				Subject:   userID.String(),
+				ID:        uuid.NewString(),
+			},
+			Role: role,
 		},
 	)
-
-	signedToken, err := token.SignedString([]byte(tokenSecret))
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
 
+// MakeRefreshToken returns a random opaque token, it's only meaningful to our database.
+func MakeRefreshToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// HashRefreshToken returns the form refresh tokens are stored and looked up in.
+// Tokens are random, so a fast hash is enough.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MakePasswordResetToken returns a random single-use token, it's made the same way as refresh tokens.
+func MakePasswordResetToken() (string, error) {
+	return MakeRefreshToken()
+}
+
+// HashPasswordResetToken returns the form password reset tokens are stored and looked up in.
+func HashPasswordResetToken(token string) string {
+	return HashRefreshToken(token)
+}
+
 func GetBearerToken(c *gin.Context) (string, error) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
@@ -66,32 +131,80 @@ func GetBearerToken(c *gin.Context) (string, error) {
 	return parts[1], nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	registeredClaims := jwt.RegisteredClaims{}
+// TokenInfo identifies a validated access token.
+type TokenInfo struct {
+	ID        string
+	UserID    uuid.UUID
+	Role      string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
 
-	token, err := jwt.ParseWithClaims(tokenString, &registeredClaims, func(token *jwt.Token) (any, error) {
-		return []byte(tokenSecret), nil
-	})
+// Revocations reports access tokens revoked before their expiry.
+type Revocations interface {
+	IsRevoked(ctx context.Context, token TokenInfo) (bool, error)
+}
+
+func ValidateJWT(ctx context.Context, tokenString string, keys *KeySet, revocations Revocations) (uuid.UUID, error) {
+	token, err := ParseJWT(ctx, tokenString, keys, revocations)
 	if err != nil {
 		return uuid.Nil, err
 	}
+	return token.UserID, nil
+}
+
+// ParseJWT validates the token like ValidateJWT and returns what identifies it.
+// revocations may be nil, then revoked tokens are accepted until they expire.
+func ParseJWT(ctx context.Context, tokenString string, keys *KeySet, revocations Revocations) (TokenInfo, error) {
+	claims := Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, &claims, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
+	if err != nil {
+		return TokenInfo{}, err
+	}
 
 	userIDString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return TokenInfo{}, err
 	}
 
 	issuer, err := token.Claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return TokenInfo{}, err
 	}
 	if issuer != string(TokenTypeAccess) {
-		return uuid.Nil, ErrInvalidIssuer
+		return TokenInfo{}, ErrInvalidIssuer
 	}
 
 	id, err := uuid.Parse(userIDString)
 	if err != nil {
-		return uuid.Nil, err
+		return TokenInfo{}, err
+	}
+
+	info := TokenInfo{
+		ID:     claims.ID,
+		UserID: id,
+		Role:   claims.Role,
+	}
+	// tokens issued before roles were introduced belong to regular users
+	if info.Role == "" {
+		info.Role = RoleUser
+	}
+	if claims.IssuedAt != nil {
+		info.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		info.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	if revocations != nil {
+		revoked, err := revocations.IsRevoked(ctx, info)
+		if err != nil {
+			return TokenInfo{}, err
+		}
+		if revoked {
+			return TokenInfo{}, ErrTokenRevoked
+		}
 	}
-	return id, nil
+	return info, nil
 }
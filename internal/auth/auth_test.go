@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -47,6 +51,134 @@ func TestCheckHashPassword(t *testing.T) {
 	}
 }
 
+func TestMakeRefreshToken(t *testing.T) {
+	first, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		got  bool
+		want bool
+	}{
+		"token_length":      {got: len(first) == 64, want: true},
+		"tokens_differ":     {got: first == second, want: false},
+		"hash_stable":       {got: HashRefreshToken(first) == HashRefreshToken(first), want: true},
+		"hash_is_not_token": {got: HashRefreshToken(first) == first, want: false},
+		"hashes_differ":     {got: HashRefreshToken(first) == HashRefreshToken(second), want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, tc.got)
+			}
+		})
+	}
+}
+
+type revokedIDs map[string]bool
+
+func (r revokedIDs) IsRevoked(ctx context.Context, token TokenInfo) (bool, error) {
+	return r[token.ID], nil
+}
+
+func TestParseJWT(t *testing.T) {
+	keys := newHMACKeySet(t, "secret")
+	otherKeys := newHMACKeySet(t, "other")
+	userID := uuid.New()
+	validToken, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedToken, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredToken, err := MakeJWT(userID, RoleUser, keys, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedInfo, err := ParseJWT(context.Background(), revokedToken, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocations := revokedIDs{revokedInfo.ID: true}
+
+	tests := map[string]struct {
+		token       string
+		keys        *KeySet
+		revocations Revocations
+		containsErr bool
+		wantErr     error
+	}{
+		"valid":              {token: validToken, keys: keys, revocations: revocations},
+		"revoked":            {token: revokedToken, keys: keys, revocations: revocations, containsErr: true, wantErr: ErrTokenRevoked},
+		"no_revocations":     {token: revokedToken, keys: keys},
+		"expired":            {token: expiredToken, keys: keys, containsErr: true, wantErr: jwt.ErrTokenExpired},
+		"unknown_key":        {token: validToken, keys: otherKeys, containsErr: true, wantErr: ErrUnknownKey},
+		"not_a_token_at_all": {token: "token", keys: keys, containsErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			info, err := ParseJWT(context.Background(), tc.token, tc.keys, tc.revocations)
+			if (err != nil) != tc.containsErr {
+				t.Fatalf("%s: expected error: %v, got: %v", name, tc.containsErr, err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if err == nil && (info.UserID != userID || info.ID == "") {
+				t.Fatalf("%s: unexpected token info: %+v", name, info)
+			}
+		})
+	}
+}
+
+func TestMakeJWTAfter(t *testing.T) {
+	keys := newHMACKeySet(t, "secret")
+	now := time.Now().UTC()
+
+	tests := map[string]struct {
+		validAfter time.Time
+	}{
+		"never_revoked":       {},
+		"revoked_long_ago":    {validAfter: now.Add(-time.Hour)},
+		"revoked_this_second": {validAfter: now},
+		"revoked_later":       {validAfter: now.Add(time.Second)},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			token, err := MakeJWTAfter(uuid.New(), RoleUser, keys, time.Minute, tc.validAfter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := ParseJWT(context.Background(), token, keys, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.IssuedAt.After(tc.validAfter) {
+				t.Fatalf("%s: expected token issued after %v, got: %v", name, tc.validAfter, info.IssuedAt)
+			}
+		})
+	}
+}
+
+func newHMACKeySet(t *testing.T, secret string) *KeySet {
+	t.Helper()
+	keys, err := NewKeySet(NewHMACKey([]byte(secret)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
 /*
    auth_test.go:20: password: mysuperstrongpassword, hash: $2a$10$EVavr/Uo6GZWIle3ZI1xuOlcbmXeGBfQWshvk2TOdkcOif2nkdCC6
    auth_test.go:20: password: qwerty12345, hash: $2a$10$CI7tmnsvcg0odFoUSztIoOQMytmuSApeWTJP4t1X.tR0AwQrcyaAC
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+var (
+	ErrUnknownKey          = errors.New("token is signed with an unknown key")
+	ErrUnexpectedAlgorithm = errors.New("token algorithm doesn't match its key")
+	ErrInvalidKey          = errors.New("invalid key")
+	ErrNotSigningKey       = errors.New("key can only verify tokens")
+)
+
+// Key is a JWT signing or verification key. Its ID is the RFC 7638 thumbprint
+// and is sent in the "kid" header of signed tokens.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signKey is nil for keys that only verify tokens
+	signKey   any
+	verifyKey any
+}
+
+// NewHMACKey makes an HS256 key, it's used both to sign and to verify.
+func NewHMACKey(secret []byte) Key {
+	return Key{
+		ID:        thumbprint(map[string]string{"kty": "oct", "k": encodeBase64(secret)}),
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// ParsePrivateKey reads an RSA (RS256) or Ed25519 (EdDSA) private key in PEM format.
+func ParsePrivateKey(pemData []byte) (Key, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return Key{}, fmt.Errorf("%w: no PEM data found", ErrInvalidKey)
+	}
+
+	var privateKey any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key, err := newPublicKey(&privateKey.PublicKey)
+		key.signKey = privateKey
+		return key, err
+	case ed25519.PrivateKey:
+		key, err := newPublicKey(privateKey.Public())
+		key.signKey = privateKey
+		return key, err
+	default:
+		return Key{}, fmt.Errorf("%w: only RSA and Ed25519 keys are supported", ErrInvalidKey)
+	}
+}
+
+// ParsePublicKey reads an RSA or Ed25519 public key in PEM format, the key only verifies tokens.
+func ParsePublicKey(pemData []byte) (Key, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return Key{}, fmt.Errorf("%w: no PEM data found", ErrInvalidKey)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return newPublicKey(publicKey)
+}
+
+func newPublicKey(publicKey any) (Key, error) {
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() < minRSAKeyBits {
+			return Key{}, fmt.Errorf("%w: RSA key must be at least %d bits", ErrInvalidKey, minRSAKeyBits)
+		}
+		return Key{
+			ID:        thumbprint(rsaJWK(publicKey)),
+			Method:    jwt.SigningMethodRS256,
+			verifyKey: publicKey,
+		}, nil
+	case ed25519.PublicKey:
+		return Key{
+			ID:        thumbprint(ed25519JWK(publicKey)),
+			Method:    jwt.SigningMethodEdDSA,
+			verifyKey: publicKey,
+		}, nil
+	default:
+		return Key{}, fmt.Errorf("%w: only RSA and Ed25519 keys are supported", ErrInvalidKey)
+	}
+}
+
+// KeySet signs tokens with a single key and verifies them with any of its keys,
+// so tokens signed with a previous key stay valid while it's being rotated out.
+type KeySet struct {
+	signing Key
+	keys    map[string]Key
+}
+
+// NewKeySet makes a set signing with signing and additionally accepting tokens signed with verifyOnly keys.
+func NewKeySet(signing Key, verifyOnly ...Key) (*KeySet, error) {
+	if signing.signKey == nil {
+		return nil, ErrNotSigningKey
+	}
+	keys := map[string]Key{signing.ID: signing}
+	for _, key := range verifyOnly {
+		keys[key.ID] = key
+	}
+	return &KeySet{signing: signing, keys: keys}, nil
+}
+
+// Sign signs the claims with the signing key.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.signKey)
+}
+
+// Algorithms returns algorithms of all keys of the set.
+func (ks *KeySet) Algorithms() []string {
+	var algorithms []string
+	for _, key := range ks.keys {
+		if !slices.Contains(algorithms, key.Method.Alg()) {
+			algorithms = append(algorithms, key.Method.Alg())
+		}
+	}
+	return algorithms
+}
+
+// Keyfunc picks the verification key by the "kid" header. The algorithm of the token must
+// be the one of the key, otherwise e.g. an RSA public key could be used as an HMAC secret.
+// HS256 tokens issued before key IDs were introduced have no "kid", any HMAC key of the set may verify them.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		return ks.hmacKeys()
+	}
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnexpectedAlgorithm
+	}
+	return key.verifyKey, nil
+}
+
+// hmacKeys returns secrets of all HMAC keys of the set, the signing one first.
+func (ks *KeySet) hmacKeys() (jwt.VerificationKeySet, error) {
+	var set jwt.VerificationKeySet
+	if ks.signing.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		set.Keys = append(set.Keys, ks.signing.verifyKey)
+	}
+	for id, key := range ks.keys {
+		if id != ks.signing.ID && key.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+			set.Keys = append(set.Keys, key.verifyKey)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return jwt.VerificationKeySet{}, ErrUnknownKey
+	}
+	return set, nil
+}
+
+// JWKS returns public keys of the set, HMAC secrets are never published.
+func (ks *KeySet) JWKS() dto.JWKSResponse {
+	response := dto.JWKSResponse{Keys: []dto.JWKResponse{}}
+	for _, key := range ks.keys {
+		var jwk map[string]string
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk = rsaJWK(publicKey)
+		case ed25519.PublicKey:
+			jwk = ed25519JWK(publicKey)
+		default:
+			continue
+		}
+		response.Keys = append(response.Keys, dto.JWKResponse{
+			Kty: jwk["kty"],
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			N:   jwk["n"],
+			E:   jwk["e"],
+			Crv: jwk["crv"],
+			X:   jwk["x"],
+		})
+	}
+	// stable order keeps responses cacheable
+	slices.SortFunc(response.Keys, func(a, b dto.JWKResponse) int {
+		if a.Kid == ks.signing.ID {
+			return -1
+		}
+		if b.Kid == ks.signing.ID {
+			return 1
+		}
+		return strings.Compare(a.Kid, b.Kid)
+	})
+	return response
+}
+
+func rsaJWK(publicKey *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"n":   encodeBase64(publicKey.N.Bytes()),
+		"e":   encodeBase64(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+}
+
+func ed25519JWK(publicKey ed25519.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"x":   encodeBase64(publicKey),
+	}
+}
+
+// thumbprint computes the RFC 7638 thumbprint of the required members of a JWK.
+// encoding/json sorts map keys, which is exactly the required member order.
+func thumbprint(jwk map[string]string) string {
+	data, _ := json.Marshal(jwk)
+	sum := sha256.Sum256(data)
+	return encodeBase64(sum[:])
+}
+
+func encodeBase64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestThumbprint(t *testing.T) {
+	tests := map[string]struct {
+		jwk  map[string]string
+		want string
+	}{
+		// RFC 7638, section 3.1
+		"rsa": {
+			jwk: map[string]string{
+				"kty": "RSA",
+				"n":   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+				"e":   "AQAB",
+			},
+			want: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		// RFC 8037, appendix A.3
+		"ed25519": {
+			jwk:  map[string]string{"kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			want: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := thumbprint(tc.jwk); got != tc.want {
+				t.Fatalf("%s: expected: %s, got: %s", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	edKey := newEd25519Key(t)
+	hmacKey := NewHMACKey([]byte("secret"))
+
+	// the RSA key was rotated out, the set still verifies its tokens
+	rsaPublicKey, err := ParsePublicKey(encodePublicKey(t, rsaKey.verifyKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeySet(edKey, rsaPublicKey, hmacKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKeys, err := NewKeySet(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownKeys, err := NewKeySet(newEd25519Key(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := uuid.New()
+	sign := func(keys *KeySet) string {
+		t.Helper()
+		token, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	// HS256 token signed with the public RSA key as the secret, the classic algorithm confusion
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Issuer: string(TokenTypeAccess), Subject: userID.String()})
+	confused.Header["kid"] = rsaKey.ID
+	confusedToken, err := confused.SignedString(encodePublicKey(t, rsaKey.verifyKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		token   string
+		wantErr error
+	}{
+		"current_key":         {token: sign(keys)},
+		"previous_key":        {token: sign(oldKeys)},
+		"unknown_key":         {token: sign(unknownKeys), wantErr: ErrUnknownKey},
+		"algorithm_confusion": {token: confusedToken, wantErr: ErrUnexpectedAlgorithm},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotUserID, err := ValidateJWT(context.Background(), tc.token, keys, nil)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if err == nil && gotUserID != userID {
+				t.Fatalf("%s: expected: %v, got: %v", name, userID, gotUserID)
+			}
+		})
+	}
+
+	if _, err := NewKeySet(rsaPublicKey); !errors.Is(err, ErrNotSigningKey) {
+		t.Fatalf("expected: %v, got: %v", ErrNotSigningKey, err)
+	}
+}
+
+func TestKeySetTokensWithoutKid(t *testing.T) {
+	hmacKeys, err := NewKeySet(NewHMACKey([]byte("secret")), NewHMACKey([]byte("previous")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKeys, err := NewKeySet(newEd25519Key(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := uuid.New()
+	// tokens issued before key IDs were introduced
+	legacy := func(method jwt.SigningMethod, key any) string {
+		t.Helper()
+		claims := jwt.RegisteredClaims{
+			Issuer:    string(TokenTypeAccess),
+			Subject:   userID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	_, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		token       string
+		keys        *KeySet
+		containsErr bool
+		wantErr     error
+	}{
+		"current_secret":    {token: legacy(jwt.SigningMethodHS256, []byte("secret")), keys: hmacKeys},
+		"previous_secret":   {token: legacy(jwt.SigningMethodHS256, []byte("previous")), keys: hmacKeys},
+		"unknown_secret":    {token: legacy(jwt.SigningMethodHS256, []byte("unknown")), keys: hmacKeys, containsErr: true, wantErr: jwt.ErrTokenSignatureInvalid},
+		"no_hmac_keys":      {token: legacy(jwt.SigningMethodHS256, []byte("secret")), keys: edKeys, containsErr: true, wantErr: jwt.ErrTokenSignatureInvalid},
+		"asymmetric_no_kid": {token: legacy(jwt.SigningMethodEdDSA, edPrivateKey), keys: edKeys, containsErr: true, wantErr: ErrUnknownKey},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotUserID, err := ValidateJWT(context.Background(), tc.token, tc.keys, nil)
+			if (err != nil) != tc.containsErr {
+				t.Fatalf("%s: expected error: %v, got: %v", name, tc.containsErr, err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if err == nil && gotUserID != userID {
+				t.Fatalf("%s: expected: %v, got: %v", name, userID, gotUserID)
+			}
+		})
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	edKey := newEd25519Key(t)
+	keys, err := NewKeySet(edKey, rsaKey, NewHMACKey([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 public keys, got: %d", len(jwks.Keys))
+	}
+	// the signing key goes first
+	if jwks.Keys[0].Kid != edKey.ID || jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].Alg != "EdDSA" || jwks.Keys[0].X == "" {
+		t.Fatalf("unexpected Ed25519 key: %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Kid != rsaKey.ID || jwks.Keys[1].Kty != "RSA" || jwks.Keys[1].Alg != "RS256" || jwks.Keys[1].E != "AQAB" {
+		t.Fatalf("unexpected RSA key: %+v", jwks.Keys[1])
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		pemData    []byte
+		wantMethod string
+		wantErr    error
+	}{
+		"rsa_pkcs1":     {pemData: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(newRSAKey(t).signKey.(*rsa.PrivateKey))}), wantMethod: "RS256"},
+		"ed25519_pkcs8": {pemData: encodePrivateKey(t, newEd25519Key(t).signKey), wantMethod: "EdDSA"},
+		"rsa_too_small": {pemData: encodePrivateKey(t, smallKey), wantErr: ErrInvalidKey},
+		"not_pem":       {pemData: []byte("secret"), wantErr: ErrInvalidKey},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			key, err := ParsePrivateKey(tc.pemData)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if err == nil && key.Method.Alg() != tc.wantMethod {
+				t.Fatalf("%s: expected: %s, got: %s", name, tc.wantMethod, key.Method.Alg())
+			}
+		})
+	}
+}
+
+func newRSAKey(t *testing.T) Key {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(encodePrivateKey(t, privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) Key {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(encodePrivateKey(t, privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encodePrivateKey(t *testing.T, privateKey any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func encodePublicKey(t *testing.T, publicKey any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenTypeMFA marks tokens proving the password was checked, they are exchanged
+// for access tokens by passing the second factor and can't be used as access tokens.
+const TokenTypeMFA TokenType = "auth-service-mfa"
+
+func MakeMFAToken(userID uuid.UUID, keys *KeySet, expiresIn time.Duration) (string, error) {
+	currentTime := time.Now().UTC()
+	return keys.Sign(
+		jwt.RegisteredClaims{
+			Issuer:    string(TokenTypeMFA),
+			IssuedAt:  jwt.NewNumericDate(currentTime),
+			ExpiresAt: jwt.NewNumericDate(currentTime.Add(expiresIn)),
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+	)
+}
+
+// ValidateMFAToken returns the user who passed the password check and the ID of the token,
+// attempts to pass the second factor are counted per token.
+func ValidateMFAToken(tokenString string, keys *KeySet) (uuid.UUID, string, error) {
+	claims := jwt.RegisteredClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, &claims, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms())); err != nil {
+		return uuid.Nil, "", err
+	}
+	if claims.Issuer != string(TokenTypeMFA) {
+		return uuid.Nil, "", ErrInvalidIssuer
+	}
+	if claims.ID == "" {
+		return uuid.Nil, "", ErrMissingTokenID
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return userID, claims.ID, nil
+}
+
+// recoveryCodeEncoding avoids padding, codes are 80 bits encoded as 16 characters.
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MakeRecoveryCodes returns n random codes formatted as XXXX-XXXX-XXXX-XXXX.
+func MakeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		encoded := recoveryCodeEncoding.EncodeToString(raw)
+		codes[i] = encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the form recovery codes are stored and looked up in. Case,
+// dashes and spaces are ignored, codes are typed by hand. Codes are random, so a fast hash is enough.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMFAToken(t *testing.T) {
+	keys := newHMACKeySet(t, "secret")
+	userID := uuid.New()
+	mfaToken, err := MakeMFAToken(userID, keys, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotUserID, tokenID, err := ValidateMFAToken(mfaToken, keys)
+	if err != nil || gotUserID != userID {
+		t.Fatalf("expected: %v, got: %v, %v", userID, gotUserID, err)
+	}
+	if tokenID == "" {
+		t.Fatalf("expected token id, got none")
+	}
+	// neither token passes for the other one
+	if _, err := ParseJWT(context.Background(), mfaToken, keys, nil); !errors.Is(err, ErrInvalidIssuer) {
+		t.Fatalf("expected: %v, got: %v", ErrInvalidIssuer, err)
+	}
+	if _, _, err := ValidateMFAToken(accessToken, keys); !errors.Is(err, ErrInvalidIssuer) {
+		t.Fatalf("expected: %v, got: %v", ErrInvalidIssuer, err)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := codes[0]
+
+	tests := map[string]struct {
+		got  bool
+		want bool
+	}{
+		"count":            {got: len(codes) == 10, want: true},
+		"format":           {got: len(code) == 19 && strings.Count(code, "-") == 3, want: true},
+		"codes_differ":     {got: codes[0] == codes[1], want: false},
+		"lower_case":       {got: HashRecoveryCode(strings.ToLower(code)) == HashRecoveryCode(code), want: true},
+		"without_dashes":   {got: HashRecoveryCode(strings.ReplaceAll(code, "-", "")) == HashRecoveryCode(code), want: true},
+		"hash_is_not_code": {got: HashRecoveryCode(code) == code, want: false},
+		"hashes_differ":    {got: HashRecoveryCode(codes[0]) == HashRecoveryCode(codes[1]), want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, tc.got)
+			}
+		})
+	}
+}
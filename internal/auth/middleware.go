@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tokenKey is the gin context key of the TokenInfo of an authenticated request.
+const tokenKey = "auth.token"
+
+// Authenticator checks access tokens of incoming requests and makes the caller
+// available to handlers through Token and UserID.
+type Authenticator struct {
+	Keys        *KeySet
+	Revocations Revocations
+}
+
+// RequireAuth rejects requests without a valid access token.
+func (a *Authenticator) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.authenticate(c) {
+			return
+		}
+		if _, ok := Token(c); !ok {
+			dto.ResponseWithError(c, http.StatusUnauthorized, ErrMissingAuthorizationHeader.Error(), nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalAuth lets anonymous requests through, but a token that is sent must be valid.
+func (a *Authenticator) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.authenticate(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticate stores the token of the request in the context, if there is one.
+// On an invalid token it responds with 401 and returns false.
+func (a *Authenticator) authenticate(c *gin.Context) bool {
+	if c.GetHeader("Authorization") == "" {
+		return true
+	}
+
+	token, err := GetBearerToken(c)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusUnauthorized, err.Error(), err)
+		c.Abort()
+		return false
+	}
+	info, err := ParseJWT(c.Request.Context(), token, a.Keys, a.Revocations)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusUnauthorized, "invalid or expired access token", err)
+		c.Abort()
+		return false
+	}
+
+	c.Set(tokenKey, info)
+	return true
+}
+
+// Token returns the access token of an authenticated request.
+func Token(c *gin.Context) (TokenInfo, bool) {
+	value, ok := c.Get(tokenKey)
+	if !ok {
+		return TokenInfo{}, false
+	}
+	info, ok := value.(TokenInfo)
+	return info, ok
+}
+
+// UserID returns the ID of the authenticated user, ok is false for anonymous requests.
+func UserID(c *gin.Context) (uuid.UUID, bool) {
+	info, ok := Token(c)
+	return info.UserID, ok
+}
+
+// MustUserID is UserID for handlers behind RequireAuth, it panics if the request isn't authenticated.
+func MustUserID(c *gin.Context) uuid.UUID {
+	userID, ok := UserID(c)
+	if !ok {
+		panic("auth: MustUserID called without RequireAuth")
+	}
+	return userID
+}
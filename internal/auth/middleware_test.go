@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestAuthenticator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys := newHMACKeySet(t, "secret")
+	userID := uuid.New()
+	validToken, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedToken, err := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedInfo, err := ParseJWT(t.Context(), revokedToken, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator := &Authenticator{Keys: keys, Revocations: revokedIDs{revokedInfo.ID: true}}
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		id, ok := UserID(c)
+		if !ok {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, id.String())
+	}
+	router.GET("/required", authenticator.RequireAuth(), handler)
+	router.GET("/optional", authenticator.OptionalAuth(), handler)
+
+	tests := map[string]struct {
+		path       string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		"required_valid":        {path: "/required", header: "Bearer " + validToken, wantStatus: http.StatusOK, wantBody: userID.String()},
+		"required_missing":      {path: "/required", wantStatus: http.StatusUnauthorized},
+		"required_revoked":      {path: "/required", header: "Bearer " + revokedToken, wantStatus: http.StatusUnauthorized},
+		"required_wrong_scheme": {path: "/required", header: "Basic " + validToken, wantStatus: http.StatusUnauthorized},
+		"optional_valid":        {path: "/optional", header: "Bearer " + validToken, wantStatus: http.StatusOK, wantBody: userID.String()},
+		"optional_missing":      {path: "/optional", wantStatus: http.StatusOK, wantBody: "anonymous"},
+		"optional_invalid":      {path: "/optional", header: "Bearer garbage", wantStatus: http.StatusUnauthorized},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("%s: expected: %d, got: %d", name, tc.wantStatus, rec.Code)
+			}
+			if tc.wantBody != "" && rec.Body.String() != tc.wantBody {
+				t.Fatalf("%s: expected: %q, got: %q", name, tc.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var ErrInsufficientRole = errors.New("insufficient permissions")
+
+// roleRanks orders roles, each role can do everything lower ranked roles can.
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether role grants everything required grants.
+func HasRole(role, required string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
+
+// RequireRole rejects requests of users below the required role. It must run after RequireAuth.
+// The role comes from the access token, so a changed role takes effect with the next token.
+func RequireRole(required string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := Token(c)
+		if !ok {
+			dto.ResponseWithError(c, http.StatusUnauthorized, ErrMissingAuthorizationHeader.Error(), nil)
+			c.Abort()
+			return
+		}
+		if !HasRole(token.Role, required) {
+			dto.ResponseWithError(c, http.StatusForbidden, ErrInsufficientRole.Error(), nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
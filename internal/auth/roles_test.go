@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys := newHMACKeySet(t, "secret")
+	authenticator := &Authenticator{Keys: keys}
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/moderation", authenticator.RequireAuth(), RequireRole(RoleModerator), ok)
+	router.GET("/unprotected", RequireRole(RoleUser), ok)
+
+	token := func(role string) string {
+		t.Helper()
+		token, err := MakeJWT(uuid.New(), role, keys, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := map[string]struct {
+		path       string
+		token      string
+		wantStatus int
+	}{
+		"admin":          {path: "/moderation", token: token(RoleAdmin), wantStatus: http.StatusOK},
+		"moderator":      {path: "/moderation", token: token(RoleModerator), wantStatus: http.StatusOK},
+		"user":           {path: "/moderation", token: token(RoleUser), wantStatus: http.StatusForbidden},
+		"without_role":   {path: "/moderation", token: token(""), wantStatus: http.StatusForbidden},
+		"unknown_role":   {path: "/moderation", token: token("root"), wantStatus: http.StatusForbidden},
+		"anonymous":      {path: "/moderation", wantStatus: http.StatusUnauthorized},
+		"no_requireauth": {path: "/unprotected", token: token(RoleAdmin), wantStatus: http.StatusUnauthorized},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("%s: expected: %d, got: %d", name, tc.wantStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -1,12 +1,25 @@
 package config
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
 	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/fetcher"
 	"github.com/englandrecoil/go-marketplace-service/internal/handlers"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecache"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecheck"
+	"github.com/englandrecoil/go-marketplace-service/internal/loginguard"
+	"github.com/englandrecoil/go-marketplace-service/internal/notify"
+	"github.com/englandrecoil/go-marketplace-service/internal/revocation"
+	"github.com/englandrecoil/go-marketplace-service/internal/storage"
 	"github.com/joho/godotenv"
 )
 
@@ -31,9 +44,253 @@ func Init() handlers.ApiConfig {
 		log.Fatal("SECRET must be set")
 	}
 
-	return handlers.ApiConfig{
-		Conn:   dbConn,
-		DB:     dbQueries,
-		Secret: secret,
+	keys := loadKeySet(secret)
+
+	storageDir := os.Getenv("STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = "uploads"
+	}
+	localStorage, err := storage.NewLocalStorage(storageDir)
+	if err != nil {
+		log.Fatalf("couldn't init storage in %s: %v", storageDir, err)
+	}
+
+	cacheDir := os.Getenv("IMAGE_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = "image-cache"
+	}
+	cacheSize := int64(constants.DefaultImageCacheSize)
+	if value := os.Getenv("IMAGE_CACHE_SIZE_MB"); value != "" {
+		sizeMB, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || sizeMB <= 0 {
+			log.Fatal("IMAGE_CACHE_SIZE_MB must be a positive number")
+		}
+		cacheSize = sizeMB * 1024 * 1024
+	}
+	imageCache, err := imagecache.New(cacheDir, cacheSize)
+	if err != nil {
+		log.Fatalf("couldn't init image cache in %s: %v", cacheDir, err)
+	}
+
+	imageFormats := imagecheck.DefaultFormats
+	if value := os.Getenv("IMAGE_FORMATS"); value != "" {
+		imageFormats, err = imagecheck.ParseFormats(value)
+		if err != nil {
+			log.Fatalf("invalid IMAGE_FORMATS: %v", err)
+		}
+	}
+
+	// remote images are checked with requests to user supplied URLs
+	imageFetcher := fetcher.New(fetcher.Options{
+		Timeout:      constants.ImageFetchTimeout,
+		MaxRedirects: constants.MaxImageRedirects,
+	})
+
+	imageChecker := handlers.ImageCheckerConfig{
+		Interval:    constants.ImageCheckInterval,
+		HideAfter:   constants.ImageCheckHideAfter,
+		Concurrency: constants.ImageCheckConcurrency,
+		BatchSize:   constants.ImageCheckBatchSize,
+	}
+	if value := os.Getenv("IMAGE_CHECK_INTERVAL"); value != "" {
+		imageChecker.Interval = parsePositiveDuration("IMAGE_CHECK_INTERVAL", value)
+	}
+	if value := os.Getenv("IMAGE_CHECK_HIDE_AFTER"); value != "" {
+		imageChecker.HideAfter = parsePositiveDuration("IMAGE_CHECK_HIDE_AFTER", value)
+	}
+	if value := os.Getenv("IMAGE_CHECK_CONCURRENCY"); value != "" {
+		concurrency, err := strconv.Atoi(value)
+		if err != nil || concurrency <= 0 {
+			log.Fatal("IMAGE_CHECK_CONCURRENCY must be a positive number")
+		}
+		imageChecker.Concurrency = concurrency
+	}
+
+	accountPurge := handlers.AccountPurgeConfig{
+		Interval:   constants.AccountPurgeInterval,
+		PurgeAfter: constants.AccountPurgeAfter,
+		BatchSize:  constants.AccountPurgeBatchSize,
+	}
+	// "0" turns the purge off, deleted accounts then stay anonymized forever
+	if value := os.Getenv("ACCOUNT_PURGE_AFTER"); value == "0" {
+		accountPurge.PurgeAfter = 0
+	} else if value != "" {
+		accountPurge.PurgeAfter = parsePositiveDuration("ACCOUNT_PURGE_AFTER", value)
+	}
+
+	revocations := revocation.New(dbQueries, constants.RevocationCacheTTL)
+
+	// several instances of the service have to share failed login counters through the database
+	var loginFailures loginguard.Store
+	switch os.Getenv("LOGIN_GUARD_STORE") {
+	case "", "memory":
+		loginFailures = loginguard.NewMemoryStore()
+	case "postgres":
+		loginFailures = loginguard.NewPostgresStore(dbQueries)
+	default:
+		log.Fatal("LOGIN_GUARD_STORE must be memory or postgres")
+	}
+	loginGuard := loginguard.New(
+		loginFailures,
+		loginguard.Policy{
+			FreeAttempts: constants.LoginFreeAttempts,
+			BaseDelay:    constants.LoginBaseDelay,
+			MaxDelay:     constants.LoginMaxDelay,
+			ResetAfter:   constants.LoginResetAfter,
+		},
+		loginguard.Policy{
+			FreeAttempts: constants.LoginIPFreeAttempts,
+			BaseDelay:    constants.LoginBaseDelay,
+			MaxDelay:     constants.LoginMaxDelay,
+			ResetAfter:   constants.LoginResetAfter,
+		},
+	)
+	// password reset requests are throttled in the same store, so they can't flood users with messages
+	resetGuard := loginguard.NewWithPrefix(
+		"reset:",
+		loginFailures,
+		loginguard.Policy{
+			FreeAttempts: constants.ResetFreeAttempts,
+			BaseDelay:    constants.ResetBaseDelay,
+			MaxDelay:     constants.ResetMaxDelay,
+			ResetAfter:   constants.LoginResetAfter,
+		},
+		loginguard.Policy{
+			FreeAttempts: constants.ResetIPFreeAttempts,
+			BaseDelay:    constants.ResetBaseDelay,
+			MaxDelay:     constants.ResetMaxDelay,
+			ResetAfter:   constants.LoginResetAfter,
+		},
+	)
+
+	// messages to users, like password reset tokens, only reach the log or a file for now.
+	// There is no default: the log notifier puts reset tokens into logs that may be shipped elsewhere.
+	var notifier notify.Notifier
+	switch os.Getenv("NOTIFIER") {
+	case "":
+		log.Fatal("NOTIFIER is not set, it must be log or file")
+	case "log":
+		notifier = notify.LogNotifier{}
+	case "file":
+		notifyFile := os.Getenv("NOTIFY_FILE")
+		if notifyFile == "" {
+			notifyFile = "notifications.log"
+		}
+		notifier = notify.NewFileNotifier(notifyFile)
+	default:
+		log.Fatal("NOTIFIER must be log or file")
+	}
+
+	apiCfg := handlers.ApiConfig{
+		Conn:    dbConn,
+		DB:      dbQueries,
+		Secret:  secret,
+		Keys:    keys,
+		Storage: localStorage,
+		Fetcher: imageFetcher,
+		ImagePolicy: imagecheck.Policy{
+			AllowedFormats: imageFormats,
+			MaxWidth:       constants.MaxImageWidth,
+			MaxHeight:      constants.MaxImageHeight,
+		},
+		ImageCache:   imageCache,
+		ProxyFetches: handlers.NewProxyFetches(constants.ProxyFailureTTL),
+		ImageChecker: imageChecker,
+		AccountPurge: accountPurge,
+		Revocations:  revocations,
+		Auth: &auth.Authenticator{
+			Keys:        keys,
+			Revocations: revocations,
+		},
+		LoginGuard: loginGuard,
+		ResetGuard: resetGuard,
+		Notifier:   notifier,
+		// logins waiting for RunPasswordResetSender
+		PasswordResets: make(chan string, constants.ResetQueueSize),
+		// client IPs are taken from X-Forwarded-For only behind these proxies
+		TrustedProxies: splitList(os.Getenv("TRUSTED_PROXIES")),
+	}
+
+	// the first admin, who can grant roles to others
+	if adminLogin := os.Getenv("ADMIN_LOGIN"); adminLogin != "" {
+		if err := apiCfg.BootstrapAdmin(context.Background(), adminLogin, os.Getenv("ADMIN_PASSWORD")); err != nil {
+			log.Fatalf("couldn't bootstrap admin %s: %v", adminLogin, err)
+		}
+	}
+
+	return apiCfg
+}
+
+// loadKeySet builds JWT keys. HS256 signs with SECRET, RS256 and EdDSA with the key from
+// JWT_PRIVATE_KEY_FILE. Keys being rotated out keep verifying tokens until they expire:
+// previous HMAC secrets are listed in JWT_PREVIOUS_SECRETS, public keys in JWT_VERIFY_KEY_FILES.
+func loadKeySet(secret string) *auth.KeySet {
+	algorithm := os.Getenv("JWT_ALGORITHM")
+	if algorithm == "" {
+		algorithm = "HS256"
+	}
+
+	var signingKey auth.Key
+	switch algorithm {
+	case "HS256":
+		signingKey = auth.NewHMACKey([]byte(secret))
+	case "RS256", "EdDSA":
+		keyFile := os.Getenv("JWT_PRIVATE_KEY_FILE")
+		if keyFile == "" {
+			log.Fatalf("JWT_PRIVATE_KEY_FILE must be set for %s", algorithm)
+		}
+		pemData, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatalf("couldn't read JWT_PRIVATE_KEY_FILE: %v", err)
+		}
+		signingKey, err = auth.ParsePrivateKey(pemData)
+		if err != nil {
+			log.Fatalf("couldn't parse JWT_PRIVATE_KEY_FILE: %v", err)
+		}
+		if signingKey.Method.Alg() != algorithm {
+			log.Fatalf("JWT_PRIVATE_KEY_FILE holds a %s key, but JWT_ALGORITHM is %s", signingKey.Method.Alg(), algorithm)
+		}
+	default:
+		log.Fatal("JWT_ALGORITHM must be one of HS256, RS256, EdDSA")
+	}
+
+	var verifyKeys []auth.Key
+	for _, previousSecret := range splitList(os.Getenv("JWT_PREVIOUS_SECRETS")) {
+		verifyKeys = append(verifyKeys, auth.NewHMACKey([]byte(previousSecret)))
+	}
+	for _, keyFile := range splitList(os.Getenv("JWT_VERIFY_KEY_FILES")) {
+		pemData, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatalf("couldn't read verification key %s: %v", keyFile, err)
+		}
+		key, err := auth.ParsePublicKey(pemData)
+		if err != nil {
+			log.Fatalf("couldn't parse verification key %s: %v", keyFile, err)
+		}
+		verifyKeys = append(verifyKeys, key)
+	}
+
+	keys, err := auth.NewKeySet(signingKey, verifyKeys...)
+	if err != nil {
+		log.Fatalf("couldn't init JWT keys: %v", err)
+	}
+	return keys
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parsePositiveDuration(name, value string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("%s must be a positive duration, e.g. 30m or 72h", name)
 	}
+	return duration
 }
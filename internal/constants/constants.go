@@ -3,15 +3,57 @@ package constants
 import "time"
 
 const (
-	MinTitleLength                    = 1
-	MaxTitleLength                    = 50
-	MinDescLength                     = 10
-	MaxDescLength                     = 750
-	MinPrice                          = 0
-	MaxPrice                          = 99999999
-	MaxImageSize                      = 10 * 1024 * 1024
-	TokenExpirationTime time.Duration = time.Minute * 15
-	MinEntropyBits                    = 60
-	MinLoginLength                    = 5
-	MaxLoginLength                    = 32
+	MinTitleLength                      = 1
+	MaxTitleLength                      = 50
+	MinDescLength                       = 10
+	MaxDescLength                       = 750
+	MinPrice                            = 0
+	MaxPrice                            = 99999999
+	MaxImageSize                        = 10 * 1024 * 1024
+	MaxImageWidth                       = 6000
+	MaxImageHeight                      = 6000
+	ImageProbeSize                      = 128 * 1024
+	DefaultImageCacheSize               = 512 * 1024 * 1024
+	ProxyFailureTTL       time.Duration = time.Minute
+	MaxAdImages                         = 10
+	TokenExpirationTime   time.Duration = time.Minute * 15
+	RefreshTokenLifetime  time.Duration = time.Hour * 24 * 30
+	RevocationCacheTTL    time.Duration = time.Second * 30
+	PasswordResetLifetime time.Duration = time.Hour
+	MFATokenLifetime      time.Duration = time.Minute * 5
+	MFATokenMaxAttempts                 = 5
+	RecoveryCodesCount                  = 10
+	TOTPIssuer                          = "Go Marketplace"
+	MinEntropyBits                      = 60
+	MinLoginLength                      = 5
+	MaxLoginLength                      = 32
+	MaxDisplayNameLength                = 50
+	MaxBioLength                        = 1000
+	MaxCityLength                       = 100
+	MaxContactLength                    = 100
+	ImageFetchTimeout     time.Duration = time.Second * 5
+	MaxImageRedirects                   = 3
+	ImageCheckInterval    time.Duration = time.Hour
+	ImageCheckHideAfter   time.Duration = time.Hour * 72
+	ImageCheckConcurrency               = 4
+	ImageCheckBatchSize                 = 100
+	LoginFreeAttempts                   = 5
+	LoginIPFreeAttempts                 = 20
+	LoginBaseDelay        time.Duration = time.Second
+	LoginMaxDelay         time.Duration = time.Minute * 15
+	LoginResetAfter       time.Duration = time.Hour
+	ResetFreeAttempts                   = 3
+	ResetIPFreeAttempts                 = 10
+	ResetBaseDelay        time.Duration = time.Minute
+	ResetMaxDelay         time.Duration = time.Hour
+	ResetQueueSize                      = 100
+	AccountPurgeAfter     time.Duration = time.Hour * 24 * 30
+	AccountPurgeInterval  time.Duration = time.Hour
+	AccountPurgeBatchSize               = 100
+	ExportPollInterval    time.Duration = time.Second * 5
+	ExportStaleAfter      time.Duration = time.Hour
+	ExportLifetime        time.Duration = time.Hour * 24
+	ExportLinkLifetime    time.Duration = time.Minute * 15
+	ExportBatchSize                     = 100
+	ShutdownTimeout       time.Duration = time.Second * 15
 )
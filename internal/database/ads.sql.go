@@ -7,13 +7,85 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const archiveUserAdvertisements = `-- name: ArchiveUserAdvertisements :exec
+UPDATE advertisements
+SET 
+  status = 'archived',
+  updated_at = $2
+WHERE user_id = $1 AND status <> 'archived'
+`
+
+type ArchiveUserAdvertisementsParams struct {
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) ArchiveUserAdvertisements(ctx context.Context, arg ArchiveUserAdvertisementsParams) error {
+	_, err := q.db.ExecContext(ctx, archiveUserAdvertisements, arg.UserID, arg.UpdatedAt)
+	return err
+}
+
+const countAdvertisements = `-- name: CountAdvertisements :one
+SELECT COUNT(*)
+FROM advertisements AS ads
+WHERE 
+  ($1::int IS NULL OR ads.price >= $1)
+  AND ($2::int IS NULL OR ads.price <= $2)
+  AND ads.status = $3
+  AND ($4::uuid IS NULL OR ads.user_id = $4)
+  AND ($5::boolean OR NOT ads.image_broken)
+  AND (
+    $6::text IS NULL
+    OR ads.search_vector @@ (websearch_to_tsquery('russian', $6) || websearch_to_tsquery('english', $6))
+  )
+  AND (
+    $7::int IS NULL
+    OR ads.category_id IN (
+      WITH RECURSIVE subcategories AS (
+        SELECT id FROM categories WHERE id = $7
+        UNION ALL
+        SELECT categories.id FROM categories
+        JOIN subcategories ON categories.parent_id = subcategories.id
+      )
+      SELECT id FROM subcategories
+    )
+  )
+`
+
+type CountAdvertisementsParams struct {
+	MinPrice      int32
+	MaxPrice      int32
+	Status        string
+	UserID        uuid.NullUUID
+	IncludeBroken bool
+	Query         sql.NullString
+	CategoryID    sql.NullInt32
+}
+
+func (q *Queries) CountAdvertisements(ctx context.Context, arg CountAdvertisementsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdvertisements,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.Status,
+		arg.UserID,
+		arg.IncludeBroken,
+		arg.Query,
+		arg.CategoryID,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAdvertisement = `-- name: CreateAdvertisement :one
-INSERT INTO advertisements(id, title, description, image_address, price, created_at, updated_at, user_id)
+INSERT INTO advertisements(id, title, description, image_address, price, created_at, updated_at, user_id, status, category_id, image_id)
 VALUES (
     gen_random_uuid(),
     $1,
@@ -22,9 +94,12 @@ VALUES (
     $4,
     $5,
     $6,
-    $7
+    $7,
+    $8,
+    $9,
+    $10
 ) 
-RETURNING id, title, description, image_address, price, created_at, updated_at, user_id
+RETURNING id, title, description, image_address, price, created_at, updated_at, user_id, status, search_vector, category_id, image_id, image_checked_at, image_check_error, image_check_failures, image_failing_since, image_broken
 `
 
 type CreateAdvertisementParams struct {
@@ -35,6 +110,9 @@ type CreateAdvertisementParams struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	UserID       uuid.UUID
+	Status       string
+	CategoryID   int32
+	ImageID      uuid.NullUUID
 }
 
 func (q *Queries) CreateAdvertisement(ctx context.Context, arg CreateAdvertisementParams) (Advertisement, error) {
@@ -46,6 +124,9 @@ func (q *Queries) CreateAdvertisement(ctx context.Context, arg CreateAdvertiseme
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.UserID,
+		arg.Status,
+		arg.CategoryID,
+		arg.ImageID,
 	)
 	var i Advertisement
 	err := row.Scan(
@@ -57,58 +138,250 @@ func (q *Queries) CreateAdvertisement(ctx context.Context, arg CreateAdvertiseme
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.Status,
+		&i.SearchVector,
+		&i.CategoryID,
+		&i.ImageID,
+		&i.ImageCheckedAt,
+		&i.ImageCheckError,
+		&i.ImageCheckFailures,
+		&i.ImageFailingSince,
+		&i.ImageBroken,
 	)
 	return i, err
 }
 
-const getAdvertisements = `-- name: GetAdvertisements :many
+const deleteAdvertisement = `-- name: DeleteAdvertisement :exec
+DELETE FROM advertisements
+WHERE id = $1
+`
+
+func (q *Queries) DeleteAdvertisement(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteAdvertisement, id)
+	return err
+}
+
+const getAdsForImageCheck = `-- name: GetAdsForImageCheck :many
+SELECT id, image_address
+FROM advertisements
+WHERE 
+  status = 'published'
+  AND image_id IS NULL
+  AND (image_checked_at IS NULL OR image_checked_at < $1)
+ORDER BY image_checked_at NULLS FIRST
+LIMIT $2
+`
+
+type GetAdsForImageCheckParams struct {
+	ImageCheckedAt sql.NullTime
+	Limit          int32
+}
+
+type GetAdsForImageCheckRow struct {
+	ID           uuid.UUID
+	ImageAddress string
+}
+
+func (q *Queries) GetAdsForImageCheck(ctx context.Context, arg GetAdsForImageCheckParams) ([]GetAdsForImageCheckRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAdsForImageCheck, arg.ImageCheckedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAdsForImageCheckRow
+	for rows.Next() {
+		var i GetAdsForImageCheckRow
+		if err := rows.Scan(&i.ID, &i.ImageAddress); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAdvertisementByID = `-- name: GetAdvertisementByID :one
 SELECT 
+  ads.id, 
   ads.title, 
   ads.description, 
   ads.image_address, 
+  ads.image_id, 
   ads.price, 
+  ads.status, 
+  ads.category_id, 
+  ads.created_at, 
+  ads.updated_at, 
   ads.user_id, 
+  ads.image_broken, 
   users.login AS author_login
 FROM advertisements AS ads
 JOIN users ON users.id = ads.user_id
+WHERE ads.id = $1
+`
+
+type GetAdvertisementByIDRow struct {
+	ID           uuid.UUID
+	Title        string
+	Description  string
+	ImageAddress string
+	ImageID      uuid.NullUUID
+	Price        int32
+	Status       string
+	CategoryID   int32
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       uuid.UUID
+	ImageBroken  bool
+	AuthorLogin  string
+}
+
+func (q *Queries) GetAdvertisementByID(ctx context.Context, id uuid.UUID) (GetAdvertisementByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getAdvertisementByID, id)
+	var i GetAdvertisementByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.ImageAddress,
+		&i.ImageID,
+		&i.Price,
+		&i.Status,
+		&i.CategoryID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ImageBroken,
+		&i.AuthorLogin,
+	)
+	return i, err
+}
+
+const getAdvertisements = `-- name: GetAdvertisements :many
+SELECT 
+  ads.id, 
+  ads.title, 
+  ads.description, 
+  ads.image_address, 
+  ads.image_id, 
+  ads.price, 
+  ads.status, 
+  ads.category_id, 
+  ads.created_at, 
+  ads.user_id, 
+  users.login AS author_login,
+  images.variant_widths AS image_variant_widths,
+  COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', $3) || websearch_to_tsquery('english', $3)), 0)::real AS search_rank
+FROM advertisements AS ads
+JOIN users ON users.id = ads.user_id
+LEFT JOIN images ON images.id = ads.image_id
 WHERE 
-  ($3::int IS NULL OR ads.price >= $3)
-  AND ($4::int IS NULL OR ads.price <= $4)
+  ($4::int IS NULL OR ads.price >= $4)
+  AND ($5::int IS NULL OR ads.price <= $5)
+  AND ads.status = $6
+  AND ($7::uuid IS NULL OR ads.user_id = $7)
+  AND ($8::boolean OR NOT ads.image_broken)
+  AND (
+    $3::text IS NULL
+    OR ads.search_vector @@ (websearch_to_tsquery('russian', $3) || websearch_to_tsquery('english', $3))
+  )
+  AND (
+    $9::int IS NULL
+    OR ads.category_id IN (
+      WITH RECURSIVE subcategories AS (
+        SELECT id FROM categories WHERE id = $9
+        UNION ALL
+        SELECT categories.id FROM categories
+        JOIN subcategories ON categories.parent_id = subcategories.id
+      )
+      SELECT id FROM subcategories
+    )
+  )
+  AND (
+    $10::uuid IS NULL
+    OR ($11 = 'price' AND $12 = 'asc'
+      AND (ads.price, ads.id) > ($13::int, $10))
+    OR ($11 = 'price' AND $12 = 'desc'
+      AND (ads.price, ads.id) < ($13::int, $10))
+    OR ($11 = 'created_at' AND $12 = 'asc'
+      AND (ads.created_at, ads.id) > ($14::timestamp, $10))
+    OR ($11 = 'created_at' AND $12 = 'desc'
+      AND (ads.created_at, ads.id) < ($14::timestamp, $10))
+    OR ($11 = 'relevance' AND $12 = 'asc'
+      AND (COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', $3) || websearch_to_tsquery('english', $3)), 0)::real, ads.id) > ($15::real, $10))
+    OR ($11 = 'relevance' AND $12 = 'desc'
+      AND (COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', $3) || websearch_to_tsquery('english', $3)), 0)::real, ads.id) < ($15::real, $10))
+  )
 ORDER BY
-  CASE WHEN $5 = 'price'      AND $6 = 'asc'  THEN ads.price     END ASC,
-  CASE WHEN $5 = 'price'      AND $6 = 'desc' THEN ads.price     END DESC,
-  CASE WHEN $5 = 'created_at' AND $6 = 'asc'  THEN ads.created_at END ASC,
-  CASE WHEN $5 = 'created_at' AND $6 = 'desc' THEN ads.created_at END DESC,
-  ads.created_at DESC
+  CASE WHEN $11 = 'price'      AND $12 = 'asc'  THEN ads.price     END ASC,
+  CASE WHEN $11 = 'price'      AND $12 = 'desc' THEN ads.price     END DESC,
+  CASE WHEN $11 = 'created_at' AND $12 = 'asc'  THEN ads.created_at END ASC,
+  CASE WHEN $11 = 'created_at' AND $12 = 'desc' THEN ads.created_at END DESC,
+  CASE WHEN $11 = 'relevance'  AND $12 = 'asc'
+    THEN COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', $3) || websearch_to_tsquery('english', $3)), 0)::real END ASC,
+  CASE WHEN $11 = 'relevance'  AND $12 = 'desc'
+    THEN COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', $3) || websearch_to_tsquery('english', $3)), 0)::real END DESC,
+  CASE WHEN $12 = 'asc'  THEN ads.id END ASC,
+  CASE WHEN $12 = 'desc' THEN ads.id END DESC
 LIMIT $1 OFFSET $2
 `
 
 type GetAdvertisementsParams struct {
-	Limit    int32
-	Offset   int32
-	MinPrice int32
-	MaxPrice int32
-	OrderBy  interface{}
-	OrderDir interface{}
+	Limit           int32
+	Offset          int32
+	Query           sql.NullString
+	MinPrice        int32
+	MaxPrice        int32
+	Status          string
+	UserID          uuid.NullUUID
+	IncludeBroken   bool
+	CategoryID      sql.NullInt32
+	CursorID        uuid.NullUUID
+	OrderBy         interface{}
+	OrderDir        interface{}
+	CursorPrice     sql.NullInt32
+	CursorCreatedAt sql.NullTime
+	CursorRank      sql.NullFloat64
 }
 
 type GetAdvertisementsRow struct {
-	Title        string
-	Description  string
-	ImageAddress string
-	Price        int32
-	UserID       uuid.UUID
-	AuthorLogin  string
+	ID                 uuid.UUID
+	Title              string
+	Description        string
+	ImageAddress       string
+	ImageID            uuid.NullUUID
+	Price              int32
+	Status             string
+	CategoryID         int32
+	CreatedAt          time.Time
+	UserID             uuid.UUID
+	AuthorLogin        string
+	ImageVariantWidths []int32
+	SearchRank         float32
 }
 
 func (q *Queries) GetAdvertisements(ctx context.Context, arg GetAdvertisementsParams) ([]GetAdvertisementsRow, error) {
 	rows, err := q.db.QueryContext(ctx, getAdvertisements,
 		arg.Limit,
 		arg.Offset,
+		arg.Query,
 		arg.MinPrice,
 		arg.MaxPrice,
+		arg.Status,
+		arg.UserID,
+		arg.IncludeBroken,
+		arg.CategoryID,
+		arg.CursorID,
 		arg.OrderBy,
 		arg.OrderDir,
+		arg.CursorPrice,
+		arg.CursorCreatedAt,
+		arg.CursorRank,
 	)
 	if err != nil {
 		return nil, err
@@ -118,12 +391,19 @@ func (q *Queries) GetAdvertisements(ctx context.Context, arg GetAdvertisementsPa
 	for rows.Next() {
 		var i GetAdvertisementsRow
 		if err := rows.Scan(
+			&i.ID,
 			&i.Title,
 			&i.Description,
 			&i.ImageAddress,
+			&i.ImageID,
 			&i.Price,
+			&i.Status,
+			&i.CategoryID,
+			&i.CreatedAt,
 			&i.UserID,
 			&i.AuthorLogin,
+			pq.Array(&i.ImageVariantWidths),
+			&i.SearchRank,
 		); err != nil {
 			return nil, err
 		}
@@ -137,3 +417,248 @@ func (q *Queries) GetAdvertisements(ctx context.Context, arg GetAdvertisementsPa
 	}
 	return items, nil
 }
+
+const getUserAdvertisements = `-- name: GetUserAdvertisements :many
+SELECT id, title, description, image_address, price, created_at, updated_at, user_id, status, search_vector, category_id, image_id, image_checked_at, image_check_error, image_check_failures, image_failing_since, image_broken FROM advertisements
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetUserAdvertisements(ctx context.Context, userID uuid.UUID) ([]Advertisement, error) {
+	rows, err := q.db.QueryContext(ctx, getUserAdvertisements, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Advertisement
+	for rows.Next() {
+		var i Advertisement
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.ImageAddress,
+			&i.Price,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Status,
+			&i.SearchVector,
+			&i.CategoryID,
+			&i.ImageID,
+			&i.ImageCheckedAt,
+			&i.ImageCheckError,
+			&i.ImageCheckFailures,
+			&i.ImageFailingSince,
+			&i.ImageBroken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordImageCheckFailure = `-- name: RecordImageCheckFailure :exec
+UPDATE advertisements
+SET 
+  image_checked_at = $2,
+  image_check_error = $3,
+  image_check_failures = image_check_failures + 1,
+  image_failing_since = COALESCE(image_failing_since, $2),
+  image_broken = COALESCE(image_failing_since, $2) <= $4::timestamp
+WHERE id = $1
+`
+
+type RecordImageCheckFailureParams struct {
+	ID              uuid.UUID
+	ImageCheckedAt  sql.NullTime
+	ImageCheckError sql.NullString
+	BrokenSince     time.Time
+}
+
+func (q *Queries) RecordImageCheckFailure(ctx context.Context, arg RecordImageCheckFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordImageCheckFailure,
+		arg.ID,
+		arg.ImageCheckedAt,
+		arg.ImageCheckError,
+		arg.BrokenSince,
+	)
+	return err
+}
+
+const recordImageCheckSuccess = `-- name: RecordImageCheckSuccess :exec
+UPDATE advertisements
+SET 
+  image_checked_at = $2,
+  image_check_error = NULL,
+  image_check_failures = 0,
+  image_failing_since = NULL,
+  image_broken = false
+WHERE id = $1
+`
+
+type RecordImageCheckSuccessParams struct {
+	ID             uuid.UUID
+	ImageCheckedAt sql.NullTime
+}
+
+func (q *Queries) RecordImageCheckSuccess(ctx context.Context, arg RecordImageCheckSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordImageCheckSuccess, arg.ID, arg.ImageCheckedAt)
+	return err
+}
+
+const resetImageCheck = `-- name: ResetImageCheck :exec
+UPDATE advertisements
+SET 
+  image_checked_at = NULL,
+  image_check_error = NULL,
+  image_check_failures = 0,
+  image_failing_since = NULL,
+  image_broken = false
+WHERE id = $1
+`
+
+func (q *Queries) ResetImageCheck(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, resetImageCheck, id)
+	return err
+}
+
+const updateAdvertisement = `-- name: UpdateAdvertisement :one
+UPDATE advertisements
+SET 
+  title = $2,
+  description = $3,
+  image_address = $4,
+  price = $5,
+  category_id = $6,
+  updated_at = $7,
+  image_id = $8
+WHERE id = $1 AND updated_at = $9
+RETURNING id, title, description, image_address, price, created_at, updated_at, user_id, status, search_vector, category_id, image_id, image_checked_at, image_check_error, image_check_failures, image_failing_since, image_broken
+`
+
+type UpdateAdvertisementParams struct {
+	ID                uuid.UUID
+	Title             string
+	Description       string
+	ImageAddress      string
+	Price             int32
+	CategoryID        int32
+	UpdatedAt         time.Time
+	ImageID           uuid.NullUUID
+	ExpectedUpdatedAt time.Time
+}
+
+func (q *Queries) UpdateAdvertisement(ctx context.Context, arg UpdateAdvertisementParams) (Advertisement, error) {
+	row := q.db.QueryRowContext(ctx, updateAdvertisement,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.ImageAddress,
+		arg.Price,
+		arg.CategoryID,
+		arg.UpdatedAt,
+		arg.ImageID,
+		arg.ExpectedUpdatedAt,
+	)
+	var i Advertisement
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.ImageAddress,
+		&i.Price,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.SearchVector,
+		&i.CategoryID,
+		&i.ImageID,
+		&i.ImageCheckedAt,
+		&i.ImageCheckError,
+		&i.ImageCheckFailures,
+		&i.ImageFailingSince,
+		&i.ImageBroken,
+	)
+	return i, err
+}
+
+const updateAdvertisementCover = `-- name: UpdateAdvertisementCover :exec
+UPDATE advertisements
+SET 
+  image_address = $2,
+  image_id = $3,
+  updated_at = $4
+WHERE id = $1
+`
+
+type UpdateAdvertisementCoverParams struct {
+	ID           uuid.UUID
+	ImageAddress string
+	ImageID      uuid.NullUUID
+	UpdatedAt    time.Time
+}
+
+func (q *Queries) UpdateAdvertisementCover(ctx context.Context, arg UpdateAdvertisementCoverParams) error {
+	_, err := q.db.ExecContext(ctx, updateAdvertisementCover,
+		arg.ID,
+		arg.ImageAddress,
+		arg.ImageID,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const updateAdvertisementStatus = `-- name: UpdateAdvertisementStatus :one
+UPDATE advertisements
+SET 
+  status = $2,
+  updated_at = $3
+WHERE id = $1 AND status = $4
+RETURNING id, title, description, image_address, price, created_at, updated_at, user_id, status, search_vector, category_id, image_id, image_checked_at, image_check_error, image_check_failures, image_failing_since, image_broken
+`
+
+type UpdateAdvertisementStatusParams struct {
+	ID             uuid.UUID
+	Status         string
+	UpdatedAt      time.Time
+	ExpectedStatus string
+}
+
+func (q *Queries) UpdateAdvertisementStatus(ctx context.Context, arg UpdateAdvertisementStatusParams) (Advertisement, error) {
+	row := q.db.QueryRowContext(ctx, updateAdvertisementStatus,
+		arg.ID,
+		arg.Status,
+		arg.UpdatedAt,
+		arg.ExpectedStatus,
+	)
+	var i Advertisement
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.ImageAddress,
+		&i.Price,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.SearchVector,
+		&i.CategoryID,
+		&i.ImageID,
+		&i.ImageCheckedAt,
+		&i.ImageCheckError,
+		&i.ImageCheckFailures,
+		&i.ImageFailingSince,
+		&i.ImageBroken,
+	)
+	return i, err
+}
@@ -5,26 +5,134 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type Advertisement struct {
+type AdImage struct {
 	ID           uuid.UUID
-	Title        string
-	Description  string
+	AdID         uuid.UUID
 	ImageAddress string
-	Price        int32
+	ImageID      uuid.NullUUID
+	Position     int32
+	IsCover      bool
 	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	UserID       uuid.UUID
+}
+
+type Advertisement struct {
+	ID                 uuid.UUID
+	Title              string
+	Description        string
+	ImageAddress       string
+	Price              int32
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	UserID             uuid.UUID
+	Status             string
+	SearchVector       interface{}
+	CategoryID         int32
+	ImageID            uuid.NullUUID
+	ImageCheckedAt     sql.NullTime
+	ImageCheckError    sql.NullString
+	ImageCheckFailures int32
+	ImageFailingSince  sql.NullTime
+	ImageBroken        bool
+}
+
+type Category struct {
+	ID       int32
+	ParentID sql.NullInt32
+	Name     string
+	Slug     string
+}
+
+type DataExport struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	StorageKey  sql.NullString
+	Size        sql.NullInt64
+	Error       sql.NullString
+	CreatedAt   time.Time
+	StartedAt   sql.NullTime
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+type Image struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	StorageKey    string
+	ContentType   string
+	Size          int32
+	CreatedAt     time.Time
+	VariantWidths []int32
+}
+
+type LoginFailure struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	RevokedAt sql.NullTime
+}
+
+type RevokedToken struct {
+	Jti       string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	RevokedAt time.Time
 }
 
 type User struct {
-	ID             uuid.UUID
-	Login          string
-	HashedPassword string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	ID                 uuid.UUID
+	Login              string
+	HashedPassword     string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	TokensValidAfter   sql.NullTime
+	Role               string
+	DisplayName        sql.NullString
+	AvatarImageID      uuid.NullUUID
+	Bio                sql.NullString
+	City               sql.NullString
+	ContactPreferences json.RawMessage
+	DeletedAt          sql.NullTime
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	CreatedAt    time.Time
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
 }
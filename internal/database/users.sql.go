@@ -7,9 +7,61 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users
+SET 
+  login = $2,
+  hashed_password = '',
+  role = 'user',
+  display_name = NULL,
+  avatar_image_id = NULL,
+  bio = NULL,
+  city = NULL,
+  contact_preferences = '{}',
+  tokens_valid_after = $3,
+  deleted_at = $4,
+  updated_at = $5
+WHERE id = $1
+`
+
+type AnonymizeUserParams struct {
+	ID               uuid.UUID
+	Login            string
+	TokensValidAfter sql.NullTime
+	DeletedAt        sql.NullTime
+	UpdatedAt        time.Time
+}
+
+func (q *Queries) AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUser,
+		arg.ID,
+		arg.Login,
+		arg.TokensValidAfter,
+		arg.DeletedAt,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const countUsersWithRole = `-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1
+`
+
+func (q *Queries) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersWithRole, role)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users(id, login, hashed_password, created_at, updated_at)
 VALUES (
@@ -19,7 +71,7 @@ VALUES (
     $3,
     $4
 )
-RETURNING id, login, hashed_password, created_at, updated_at
+RETURNING id, login, hashed_password, created_at, updated_at, tokens_valid_after, role, display_name, avatar_image_id, bio, city, contact_preferences, deleted_at
 `
 
 type CreateUserParams struct {
@@ -43,12 +95,56 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.HashedPassword,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.Role,
+		&i.DisplayName,
+		&i.AvatarImageID,
+		&i.Bio,
+		&i.City,
+		&i.ContactPreferences,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, login, hashed_password, created_at, updated_at, tokens_valid_after, role, display_name, avatar_image_id, bio, city, contact_preferences, deleted_at FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Login,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.Role,
+		&i.DisplayName,
+		&i.AvatarImageID,
+		&i.Bio,
+		&i.City,
+		&i.ContactPreferences,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getUserByLogin = `-- name: GetUserByLogin :one
-SELECT id, login, hashed_password, created_at, updated_at FROM users
+SELECT id, login, hashed_password, created_at, updated_at, tokens_valid_after, role, display_name, avatar_image_id, bio, city, contact_preferences, deleted_at FROM users
 WHERE login = $1
 `
 
@@ -61,6 +157,213 @@ func (q *Queries) GetUserByLogin(ctx context.Context, login string) (User, error
 		&i.HashedPassword,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.Role,
+		&i.DisplayName,
+		&i.AvatarImageID,
+		&i.Bio,
+		&i.City,
+		&i.ContactPreferences,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserTokensValidAfter = `-- name: GetUserTokensValidAfter :one
+SELECT tokens_valid_after FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserTokensValidAfter(ctx context.Context, id uuid.UUID) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getUserTokensValidAfter, id)
+	var tokens_valid_after sql.NullTime
+	err := row.Scan(&tokens_valid_after)
+	return tokens_valid_after, err
+}
+
+const getUsersToPurge = `-- name: GetUsersToPurge :many
+SELECT id FROM users
+WHERE deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type GetUsersToPurgeParams struct {
+	DeletedAt sql.NullTime
+	Limit     int32
+}
+
+func (q *Queries) GetUsersToPurge(ctx context.Context, arg GetUsersToPurgeParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersToPurge, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUsersWithRole = `-- name: LockUsersWithRole :many
+SELECT id FROM users
+WHERE role = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUsersWithRole(ctx context.Context, role string) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, lockUsersWithRole, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET 
+  hashed_password = $2,
+  updated_at = $3
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+	UpdatedAt      time.Time
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword, arg.UpdatedAt)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET 
+  display_name = $2,
+  avatar_image_id = $3,
+  bio = $4,
+  city = $5,
+  contact_preferences = $6,
+  updated_at = $7
+WHERE id = $1
+RETURNING id, login, hashed_password, created_at, updated_at, tokens_valid_after, role, display_name, avatar_image_id, bio, city, contact_preferences, deleted_at
+`
+
+type UpdateUserProfileParams struct {
+	ID                 uuid.UUID
+	DisplayName        sql.NullString
+	AvatarImageID      uuid.NullUUID
+	Bio                sql.NullString
+	City               sql.NullString
+	ContactPreferences json.RawMessage
+	UpdatedAt          time.Time
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.DisplayName,
+		arg.AvatarImageID,
+		arg.Bio,
+		arg.City,
+		arg.ContactPreferences,
+		arg.UpdatedAt,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Login,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.Role,
+		&i.DisplayName,
+		&i.AvatarImageID,
+		&i.Bio,
+		&i.City,
+		&i.ContactPreferences,
+		&i.DeletedAt,
 	)
 	return i, err
 }
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET 
+  role = $2,
+  updated_at = $3
+WHERE login = $1
+RETURNING id, login, hashed_password, created_at, updated_at, tokens_valid_after, role, display_name, avatar_image_id, bio, city, contact_preferences, deleted_at
+`
+
+type UpdateUserRoleParams struct {
+	Login     string
+	Role      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Login, arg.Role, arg.UpdatedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Login,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.Role,
+		&i.DisplayName,
+		&i.AvatarImageID,
+		&i.Bio,
+		&i.City,
+		&i.ContactPreferences,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUserTokensValidAfter = `-- name: UpdateUserTokensValidAfter :exec
+UPDATE users
+SET tokens_valid_after = $2
+WHERE id = $1
+`
+
+type UpdateUserTokensValidAfterParams struct {
+	ID               uuid.UUID
+	TokensValidAfter sql.NullTime
+}
+
+func (q *Queries) UpdateUserTokensValidAfter(ctx context.Context, arg UpdateUserTokensValidAfterParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserTokensValidAfter, arg.ID, arg.TokensValidAfter)
+	return err
+}
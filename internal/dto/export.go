@@ -0,0 +1,110 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types below are the files of a personal data export archive. Password hashes,
+// TOTP secrets and hashes of tokens and codes are secrets of the service and are left out.
+
+type ExportUser struct {
+	ID                 uuid.UUID       `json:"id"`
+	Login              string          `json:"login"`
+	Role               string          `json:"role"`
+	DisplayName        string          `json:"display_name,omitempty"`
+	AvatarImageID      *uuid.UUID      `json:"avatar_image_id,omitempty"`
+	Bio                string          `json:"bio,omitempty"`
+	City               string          `json:"city,omitempty"`
+	ContactPreferences json.RawMessage `json:"contact_preferences"`
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+	TokensValidAfter   *time.Time      `json:"tokens_valid_after,omitempty"`
+}
+
+type ExportAdvertisement struct {
+	ID              uuid.UUID       `json:"id"`
+	Title           string          `json:"title"`
+	Description     string          `json:"description"`
+	ImageAddress    string          `json:"image_address"`
+	ImageID         *uuid.UUID      `json:"image_id,omitempty"`
+	Price           int             `json:"price"`
+	CategoryID      int             `json:"category_id"`
+	Status          string          `json:"status"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	ImageCheckedAt  *time.Time      `json:"image_checked_at,omitempty"`
+	ImageCheckError string          `json:"image_check_error,omitempty"`
+	ImageBroken     bool            `json:"image_broken"`
+	Images          []ExportAdImage `json:"images"`
+}
+
+type ExportAdImage struct {
+	ID           uuid.UUID  `json:"id"`
+	ImageAddress string     `json:"image_address"`
+	ImageID      *uuid.UUID `json:"image_id,omitempty"`
+	Position     int        `json:"position"`
+	IsCover      bool       `json:"is_cover"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ExportImage struct {
+	ID          uuid.UUID `json:"id"`
+	File        string    `json:"file"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportSession struct {
+	ID        uuid.UUID  `json:"id"`
+	FamilyID  uuid.UUID  `json:"family_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type ExportSecurity struct {
+	TwoFactor      *ExportTwoFactor      `json:"two_factor,omitempty"`
+	RecoveryCodes  []ExportRecoveryCode  `json:"recovery_codes"`
+	PasswordResets []ExportPasswordReset `json:"password_resets"`
+	RevokedTokens  []ExportRevokedToken  `json:"revoked_tokens"`
+	LoginFailures  *ExportLoginFailures  `json:"login_failures,omitempty"`
+}
+
+type ExportTwoFactor struct {
+	CreatedAt   time.Time  `json:"created_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+}
+
+type ExportRecoveryCode struct {
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+type ExportPasswordReset struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+type ExportRevokedToken struct {
+	ID        string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ExportLoginFailures struct {
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+}
+
+type ExportDataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
@@ -5,18 +5,126 @@ type CredentialsRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-type CreateAdsRequest struct {
-	Title        string `json:"title" binding:"required"`
-	Description  string `json:"description" binding:"required"`
-	ImageAddress string `json:"image_address" binding:"required"`
-	Price        int    `json:"price" binding:"required"`
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
-type GetAdsQueryParamsRequest struct {
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Login string `json:"login" binding:"required"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type EnrollTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"`
+}
+
+type MFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type UpdateProfileRequest struct {
+	DisplayName        *string             `json:"display_name"`
+	AvatarImageID      *string             `json:"avatar_image_id"`
+	Bio                *string             `json:"bio"`
+	City               *string             `json:"city"`
+	ContactPreferences *ContactPreferences `json:"contact_preferences"`
+}
+
+// ContactPreferences tell buyers how to reach the seller, it's stored as JSON in users.contact_preferences.
+type ContactPreferences struct {
+	Phone     string `json:"phone,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Telegram  string `json:"telegram,omitempty"`
+	Preferred string `json:"preferred,omitempty" enums:"chat,phone,email,telegram"`
+	Public    bool   `json:"public"`
+}
+
+type UserAdsQueryParamsRequest struct {
 	Page     int    `form:"page" default:"1"`
+	Cursor   string `form:"cursor"`
 	PageSize int    `form:"page_size"`
-	MinPrice *int   `form:"min_price"`
-	MaxPrice *int   `form:"max_price"`
 	SortBy   string `form:"sort_by"`
 	Order    string `form:"order"`
 }
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
+type CreateAdsRequest struct {
+	Title        string           `json:"title" binding:"required"`
+	Description  string           `json:"description" binding:"required"`
+	ImageAddress string           `json:"image_address"`
+	ImageID      string           `json:"image_id"`
+	Price        int              `json:"price" binding:"required"`
+	CategoryID   int              `json:"category_id" binding:"required"`
+	Status       string           `json:"status"`
+	Images       []AdImageRequest `json:"images"`
+}
+
+type AdImageRequest struct {
+	ImageAddress string `json:"image_address"`
+	ImageID      string `json:"image_id"`
+}
+
+type UpdateAdImagesRequest struct {
+	Images     []AdImageRequest `json:"images" binding:"required"`
+	CoverIndex int              `json:"cover_index"`
+}
+
+type UpdateAdsRequest struct {
+	Title        *string `json:"title"`
+	Description  *string `json:"description"`
+	ImageAddress *string `json:"image_address"`
+	ImageID      *string `json:"image_id"`
+	Price        *int    `json:"price"`
+	CategoryID   *int    `json:"category_id"`
+}
+
+type UpdateAdStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+type GetAdsQueryParamsRequest struct {
+	Q         string `form:"q"`
+	Page      int    `form:"page" default:"1"`
+	Cursor    string `form:"cursor"`
+	PageSize  int    `form:"page_size"`
+	MinPrice  *int   `form:"min_price"`
+	MaxPrice  *int   `form:"max_price"`
+	Category  *int   `form:"category"`
+	SortBy    string `form:"sort_by"`
+	Order     string `form:"order"`
+	Status    string `form:"status"`
+	Mine      bool   `form:"mine"`
+	WithTotal bool   `form:"with_total,default=true"`
+}
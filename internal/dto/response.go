@@ -19,26 +19,164 @@ type RegisterResponse struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type UserRoleResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Login     string    `json:"login"`
+	Role      string    `json:"role"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type MFARequiredResponse struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type UserProfileResponse struct {
+	ID                 uuid.UUID              `json:"id"`
+	Login              string                 `json:"login"`
+	Role               string                 `json:"role"`
+	DisplayName        string                 `json:"display_name,omitempty"`
+	AvatarImageID      *uuid.UUID             `json:"avatar_image_id,omitempty"`
+	AvatarURL          string                 `json:"avatar_url,omitempty"`
+	AvatarVariants     []ImageVariantResponse `json:"avatar_variants,omitempty"`
+	Bio                string                 `json:"bio,omitempty"`
+	City               string                 `json:"city,omitempty"`
+	ContactPreferences ContactPreferences     `json:"contact_preferences"`
+	CreatedAt          time.Time              `json:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+}
+
+type PublicUserProfileResponse struct {
+	Login          string                 `json:"login"`
+	DisplayName    string                 `json:"display_name,omitempty"`
+	AvatarURL      string                 `json:"avatar_url,omitempty"`
+	AvatarVariants []ImageVariantResponse `json:"avatar_variants,omitempty"`
+	Bio            string                 `json:"bio,omitempty"`
+	City           string                 `json:"city,omitempty"`
+	Contacts       *ContactPreferences    `json:"contacts,omitempty"`
+	RegisteredAt   time.Time              `json:"registered_at"`
+	ActiveAdsCount int64                  `json:"active_ads_count"`
+	Ads            GetAdsListResponse     `json:"ads"`
 }
 
 type CreateAdsResponse struct {
-	ID           string    `json:"id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	ImageAddress string    `json:"image_address"`
-	Price        int       `json:"price"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	ImageAddress string            `json:"image_address"`
+	Price        int               `json:"price"`
+	CategoryID   int               `json:"category_id"`
+	Status       string            `json:"status"`
+	CreatedAt    time.Time         `json:"created_at"`
+	Images       []AdImageResponse `json:"images"`
 }
 
 type GetAdsResponse struct {
-	Title        string `json:"title"`
-	Description  string `json:"description"`
+	ID            string                 `json:"id"`
+	Title         string                 `json:"title"`
+	Description   string                 `json:"description"`
+	ImageAddress  string                 `json:"image_address"`
+	AuthorLogin   string                 `json:"author_login"`
+	Price         int                    `json:"price"`
+	CategoryID    int                    `json:"category_id"`
+	Status        string                 `json:"status"`
+	CreatedAt     time.Time              `json:"created_at"`
+	IsOwner       *bool                  `json:"is_owner,omitempty"`
+	ImageVariants []ImageVariantResponse `json:"image_variants,omitempty"`
+}
+
+type GetAdsListResponse struct {
+	Items      []GetAdsResponse `json:"items"`
+	Page       int              `json:"page,omitempty"`
+	PageSize   int              `json:"page_size"`
+	Total      *int64           `json:"total,omitempty"`
+	HasNext    bool             `json:"has_next"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type GetAdResponse struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	Description  string            `json:"description"`
+	ImageAddress string            `json:"image_address"`
+	AuthorLogin  string            `json:"author_login"`
+	Price        int               `json:"price"`
+	CategoryID   int               `json:"category_id"`
+	Status       string            `json:"status"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	IsOwner      *bool             `json:"is_owner,omitempty"`
+	ImageBroken  bool              `json:"image_broken,omitempty"`
+	Images       []AdImageResponse `json:"images"`
+}
+
+type AdImageResponse struct {
+	ID           string `json:"id"`
 	ImageAddress string `json:"image_address"`
-	AuthorLogin  string `json:"author_login"`
-	Price        int    `json:"price"`
-	IsOwner      *bool  `json:"is_owner,omitempty"`
+	ImageID      string `json:"image_id,omitempty"`
+	Position     int    `json:"position"`
+	IsCover      bool   `json:"is_cover"`
+}
+
+type CategoryResponse struct {
+	ID       int                `json:"id"`
+	Name     string             `json:"name"`
+	Slug     string             `json:"slug"`
+	Children []CategoryResponse `json:"children"`
+}
+
+type UploadImageResponse struct {
+	ID          string                 `json:"id"`
+	URL         string                 `json:"url"`
+	ContentType string                 `json:"content_type"`
+	Size        int                    `json:"size"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Variants    []ImageVariantResponse `json:"variants"`
+}
+
+type ImageVariantResponse struct {
+	Width int    `json:"width"`
+	URL   string `json:"url"`
+}
+
+type DataExportResponse struct {
+	ID                   uuid.UUID  `json:"id"`
+	Status               string     `json:"status" enums:"pending,processing,ready,failed"`
+	CreatedAt            time.Time  `json:"created_at"`
+	CompletedAt          *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+	Size                 int64      `json:"size,omitempty"`
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+// JWKSResponse is a JSON Web Key Set (RFC 7517) of keys verifying our access tokens.
+type JWKSResponse struct {
+	Keys []JWKResponse `json:"keys"`
+}
+
+type JWKResponse struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
 }
 
 func ResponseWithError(c *gin.Context, code int, errMsg string, err error) {
@@ -0,0 +1,137 @@
+// Package fetcher makes HTTP requests to user supplied URLs
+// without letting them reach the internal network of the service.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrForbiddenAddress  = errors.New("address points to a private network")
+	ErrUnsupportedScheme = errors.New("only http and https urls are allowed")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+)
+
+type Options struct {
+	// Timeout limits the whole request including redirects and reading the body.
+	Timeout      time.Duration
+	MaxRedirects int
+	// AllowPrivate turns off address checks, it's meant for tests against a local server only.
+	AllowPrivate bool
+}
+
+// Fetcher is an HTTP client that refuses to connect to loopback, private, link-local
+// and other special-purpose addresses.
+type Fetcher struct {
+	client *http.Client
+}
+
+func New(opts Options) *Fetcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		// the check runs on the address that is actually dialed, so neither redirects
+		// nor DNS rebinding between validation and connection can get around it
+		dialer.Control = checkDialAddress
+	}
+
+	transport := &http.Transport{
+		// a proxy would connect on our behalf and skip the address check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > opts.MaxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkURL(req.URL)
+			},
+		},
+	}
+}
+
+// Do sends the request, errors from checks can be matched with errors.Is.
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	if err := checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	return f.client.Do(req)
+}
+
+// Head is a shortcut for a HEAD request bound to ctx.
+func (f *Fetcher) Head(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.Do(req)
+}
+
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	if u.Hostname() == "" {
+		return &url.Error{Op: "parse", URL: u.String(), Err: errors.New("missing host")}
+	}
+	return nil
+}
+
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	if network != "tcp4" && network != "tcp6" {
+		return ErrForbiddenAddress
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return ErrForbiddenAddress
+	}
+	if !IsPublicAddr(addrPort.Addr()) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// special-purpose ranges that aren't covered by netip.Addr helpers
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, includes broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may map to internal IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// IsPublicAddr reports whether addr is a globally routable unicast address.
+func IsPublicAddr(addr netip.Addr) bool {
+	// ::ffff:127.0.0.1 must be treated as 127.0.0.1
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
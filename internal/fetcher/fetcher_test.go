@@ -0,0 +1,101 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := map[string]struct {
+		addr string
+		want bool
+	}{
+		"public_ipv4":         {addr: "140.82.121.4", want: true},
+		"public_ipv6":         {addr: "2606:50c0:8000::153", want: true},
+		"loopback":            {addr: "127.0.0.1", want: false},
+		"loopback_ipv6":       {addr: "::1", want: false},
+		"mapped_loopback":     {addr: "::ffff:127.0.0.1", want: false},
+		"private":             {addr: "10.0.0.5", want: false},
+		"docker_network":      {addr: "172.18.0.2", want: false},
+		"cloud_metadata":      {addr: "169.254.169.254", want: false},
+		"link_local_ipv6":     {addr: "fe80::1", want: false},
+		"unique_local_ipv6":   {addr: "fd00::1", want: false},
+		"unspecified":         {addr: "0.0.0.0", want: false},
+		"carrier_grade_nat":   {addr: "100.64.0.1", want: false},
+		"broadcast":           {addr: "255.255.255.255", want: false},
+		"multicast":           {addr: "224.0.0.1", want: false},
+		"nat64_private_ipv4":  {addr: "64:ff9b::a00:5", want: false},
+		"documentation_range": {addr: "192.0.2.10", want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := IsPublicAddr(netip.MustParseAddr(tc.addr))
+			if got != tc.want {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFetcherRejectsLocalServer(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	f := New(Options{Timeout: time.Second, MaxRedirects: 3})
+	_, err := f.Head(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected: %v, got: %v", ErrForbiddenAddress, err)
+	}
+	if requested {
+		t.Fatal("request must not reach the server")
+	}
+}
+
+func TestFetcherRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/to-file", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	})
+	mux.HandleFunc("/once", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := New(Options{Timeout: time.Second, MaxRedirects: 3, AllowPrivate: true})
+	tests := map[string]struct {
+		path    string
+		wantErr error
+	}{
+		"single_redirect":    {path: "/once", wantErr: nil},
+		"redirect_loop":      {path: "/loop", wantErr: ErrTooManyRedirects},
+		"redirect_to_file":   {path: "/to-file", wantErr: ErrUnsupportedScheme},
+		"no_redirect_at_all": {path: "/ok", wantErr: nil},
+		"missing_page_is_ok": {path: "/missing", wantErr: nil},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := f.Head(context.Background(), server.URL+tc.path)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if err == nil {
+				res.Body.Close()
+			}
+		})
+	}
+}
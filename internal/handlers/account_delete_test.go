@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDeletedLogin(t *testing.T) {
+	userID := uuid.MustParse("5b9c2f4e-8d1a-4c3b-9e7f-0a1b2c3d4e5f")
+
+	login := deletedLogin(userID)
+	if login != "deleted-5b9c2f4e8d1a4c3b9e7f0a1b2c3d4e5f" {
+		t.Fatalf("expected: %v, got: %v", "deleted-5b9c2f4e8d1a4c3b9e7f0a1b2c3d4e5f", login)
+	}
+	// nobody can register the login of a deleted account
+	if err := validateLogin(login); !errors.Is(err, ErrInvalidLoginLength) {
+		t.Fatalf("expected: %v, got: %v", ErrInvalidLoginLength, err)
+	}
+	if other := deletedLogin(uuid.New()); other == login {
+		t.Fatalf("expected different logins for different users, got: %v", other)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/thumbnail"
+	"github.com/google/uuid"
+)
+
+// AccountPurgeConfig controls hard deletion of accounts their owners deleted.
+type AccountPurgeConfig struct {
+	Interval time.Duration
+	// PurgeAfter is the grace period after the deletion, zero keeps anonymized accounts forever
+	PurgeAfter time.Duration
+	BatchSize  int
+}
+
+// RunAccountPurger deletes the data of deleted accounts once their grace period is over,
+// until ctx is cancelled.
+func (cfg *ApiConfig) RunAccountPurger(ctx context.Context) {
+	if cfg.AccountPurge.PurgeAfter == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.AccountPurge.Interval)
+	defer ticker.Stop()
+
+	for {
+		deletedBefore := time.Now().UTC().Add(-cfg.AccountPurge.PurgeAfter)
+		if err := cfg.purgeDeletedAccounts(ctx, deletedBefore); err != nil && ctx.Err() == nil {
+			log.Printf("couldn't purge deleted accounts: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *ApiConfig) purgeDeletedAccounts(ctx context.Context, deletedBefore time.Time) error {
+	for {
+		userIDs, err := cfg.DB.GetUsersToPurge(
+			ctx,
+			database.GetUsersToPurgeParams{
+				DeletedAt: sql.NullTime{Time: deletedBefore, Valid: true},
+				Limit:     int32(cfg.AccountPurge.BatchSize),
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		// a failed purge would return the same user again, so the pass stops on it
+		for _, userID := range userIDs {
+			if err := cfg.purgeAccount(ctx, userID); err != nil {
+				return err
+			}
+		}
+		if len(userIDs) < cfg.AccountPurge.BatchSize {
+			return nil
+		}
+	}
+}
+
+// purgeAccount removes uploaded files before the rows: if deleting the user fails, the next
+// pass deletes the files again, the other way round they would be left without an owner.
+func (cfg *ApiConfig) purgeAccount(ctx context.Context, userID uuid.UUID) error {
+	images, err := cfg.DB.GetUserImages(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		if err := cfg.Storage.Delete(ctx, image.StorageKey); err != nil {
+			return err
+		}
+		variantExtension := imageExtensions[thumbnail.VariantContentType(image.ContentType)]
+		for _, width := range image.VariantWidths {
+			if err := cfg.Storage.Delete(ctx, variantStorageKey(image.ID, int(width), variantExtension)); err != nil {
+				return err
+			}
+		}
+	}
+
+	exports, err := cfg.DB.GetUserDataExports(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if export.StorageKey.Valid {
+			if err := cfg.Storage.Delete(ctx, export.StorageKey.String); err != nil {
+				return err
+			}
+		}
+	}
+
+	// ads, images, exports and tokens go along with the user by ON DELETE CASCADE
+	return cfg.DB.DeleteUser(ctx, userID)
+}
@@ -1,53 +1,169 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/fetcher"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecheck"
 )
 
 func TestValidateImage(t *testing.T) {
+	pngImage := encodeTestImage(t, png.Encode, 40, 30)
+	gifImage := encodeTestImage(t, func(w io.Writer, m image.Image) error { return gif.Encode(w, m, nil) }, 40, 30)
+	// padding after the image data makes the file too big while the header stays valid
+	hugeImage := append(encodeTestImage(t, png.Encode, 40, 30), make([]byte, constants.MaxImageSize)...)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/avatar.png":
+			w.Header().Set("Content-Type", "image/png")
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(pngImage))
+		case "/no-ranges.png":
+			// no Content-Length and no ranges, the size has to be counted
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngImage)
+			w.(http.Flusher).Flush()
+		case "/octet-stream":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngImage)
+		case "/fake.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(pngImage)
+		case "/animation.gif":
+			w.Header().Set("Content-Type", "image/gif")
+			w.Write(gifImage)
+		case "/huge.png":
+			w.Header().Set("Content-Type", "image/png")
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(hugeImage))
+		case "/huge-no-ranges.png":
+			// nothing tells the size upfront
+			w.Header().Set("Content-Type", "image/png")
+			w.(http.Flusher).Flush()
+			w.Write(hugeImage)
+		case "/corrupt.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngImage[:20])
+		case "/moved":
+			http.Redirect(w, r, "/avatar.png", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/missing.png":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>not an image</body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := ApiConfig{
+		Fetcher:     fetcher.New(fetcher.Options{Timeout: time.Second, MaxRedirects: 3, AllowPrivate: true}),
+		ImagePolicy: imagecheck.Policy{AllowedFormats: []string{"jpeg", "png"}, MaxWidth: 100, MaxHeight: 100},
+	}
+	narrowPolicy := imagecheck.Policy{AllowedFormats: []string{"jpeg", "png"}, MaxWidth: 30, MaxHeight: 30}
+	gifPolicy := imagecheck.Policy{AllowedFormats: []string{"gif"}, MaxWidth: 100, MaxHeight: 100}
 	tests := map[string]struct {
 		url         string
+		policy      *imagecheck.Policy
 		containsErr bool
+		wantErr     error
 	}{
-		"valid_image_url":   {url: "https://avatars.githubusercontent.com/u/93984891?v=4", containsErr: false},
-		"invalid_image_url": {url: "https://broken-image-url", containsErr: true},
-		"not_an_image_url":  {url: "https://github.com/englandrecoil", containsErr: true},
+		"valid_image_url":      {url: server.URL + "/avatar.png"},
+		"without_ranges":       {url: server.URL + "/no-ranges.png"},
+		"generic_type":         {url: server.URL + "/octet-stream"},
+		"redirect_to_image":    {url: server.URL + "/moved"},
+		"allowed_gif":          {url: server.URL + "/animation.gif", policy: &gifPolicy},
+		"too_wide":             {url: server.URL + "/avatar.png", policy: &narrowPolicy, containsErr: true, wantErr: imagecheck.ErrDimensionsTooBig},
+		"not_allowed_gif":      {url: server.URL + "/animation.gif", containsErr: true, wantErr: imagecheck.ErrFormatNotAllowed},
+		"invalid_image_url":    {url: "https://broken-image-url.invalid", containsErr: true},
+		"not_an_image_url":     {url: server.URL + "/englandrecoil", containsErr: true, wantErr: ErrInvalidImageFormat},
+		"corrupt_image":        {url: server.URL + "/corrupt.png", containsErr: true, wantErr: ErrInvalidImageFormat},
+		"mismatched_type":      {url: server.URL + "/fake.jpg", containsErr: true, wantErr: ErrImageTypeMismatch},
+		"image_too_big":        {url: server.URL + "/huge.png", containsErr: true, wantErr: ErrImageTooBig},
+		"too_big_unknown_size": {url: server.URL + "/huge-no-ranges.png", containsErr: true, wantErr: ErrImageTooBig},
+		"missing_image":        {url: server.URL + "/missing.png", containsErr: true},
+		"too_many_redirects":   {url: server.URL + "/loop", containsErr: true, wantErr: fetcher.ErrTooManyRedirects},
+		"unsupported_scheme":   {url: "ftp://example.com/image.png", containsErr: true, wantErr: fetcher.ErrUnsupportedScheme},
+		"not_an_absolute_url":  {url: "image.png", containsErr: true},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			err := validateImage(tc.url)
+			cfg := cfg
+			if tc.policy != nil {
+				cfg.ImagePolicy = *tc.policy
+			}
+			err := cfg.validateImage(context.Background(), tc.url)
 			if err != nil && tc.containsErr == false {
 				t.Fatalf("%s: expected no error, got: %v", name, err)
 			}
 			if err == nil && tc.containsErr == true {
 				t.Fatalf("%s: error expected, got nothing", name)
 			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
 		})
 	}
 }
 
-func TestValidateAdParams(t *testing.T) {
+func TestValidateImageRejectsInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	}))
+	defer server.Close()
+
+	cfg := ApiConfig{
+		Fetcher:     fetcher.New(fetcher.Options{Timeout: time.Second, MaxRedirects: 3}),
+		ImagePolicy: imagecheck.Policy{AllowedFormats: imagecheck.DefaultFormats, MaxWidth: 100, MaxHeight: 100},
+	}
+	err := cfg.validateImage(context.Background(), server.URL+"/avatar.png")
+	if !errors.Is(err, fetcher.ErrForbiddenAddress) {
+		t.Fatalf("expected: %v, got: %v", fetcher.ErrForbiddenAddress, err)
+	}
+}
+
+func TestValidateAdDetails(t *testing.T) {
 	tests := map[string]struct {
-		title        string
-		description  string
-		imageAddress string
-		price        int
-		wantErr      error
+		title       string
+		description string
+		price       int
+		wantErr     error
 	}{
-		"invalid_length_title":       {title: "", description: "description", imageAddress: "https://iili.io/2g8pbwu.jpg", price: 55, wantErr: ErrInvalidLengthTitle},
-		"invalid_length_description": {title: "myadtitle", description: "desc", imageAddress: "https://iili.io/2g8pbwu.jpg", price: 143, wantErr: ErrInvalidLengthDescription},
-		"invalid_price":              {title: "myadtitle2", description: "super cool description", imageAddress: "https://iili.io/2g8pbwu.jpg", price: -100, wantErr: ErrInvalidPrice},
-		"valid_ad_parameters":        {title: "Macbook Air 13 M1", description: "Super cool and brand new laptop (2020 lol)", imageAddress: "https://iili.io/2g8pbwu.jpg", price: 78900, wantErr: nil},
+		"invalid_length_title":       {title: "", description: "description", price: 55, wantErr: ErrInvalidLengthTitle},
+		"invalid_length_description": {title: "myadtitle", description: "desc", price: 143, wantErr: ErrInvalidLengthDescription},
+		"invalid_price":              {title: "myadtitle2", description: "super cool description", price: -100, wantErr: ErrInvalidPrice},
+		"valid_ad_parameters":        {title: "Macbook Air 13 M1", description: "Super cool and brand new laptop (2020 lol)", price: 78900, wantErr: nil},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			err := validateAdParams(tc.title, tc.description, tc.imageAddress, tc.price)
+			err := validateAdDetails(tc.title, tc.description, tc.price)
 			if !errors.Is(err, tc.wantErr) {
 				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
 			}
 		})
 	}
 }
+
+func encodeTestImage(t *testing.T, encode func(io.Writer, image.Image) error, width, height int) []byte {
+	t.Helper()
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette.Plan9)
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTooManyAdImages = fmt.Errorf("advertisement can't have more than %d images", constants.MaxAdImages)
+	ErrInvalidCover    = errors.New("cover_index is out of range")
+)
+
+// adImage is a validated gallery entry ready to be stored.
+type adImage struct {
+	Address string
+	ImageID uuid.NullUUID
+}
+
+// resolveAdImages validates requested gallery images: uploaded ones must belong to userID,
+// remote ones are checked with cfg.validateImage unless they are in knownAddresses already.
+// On failure the error response is already written and ok is false.
+func (cfg *ApiConfig) resolveAdImages(c *gin.Context, userID uuid.UUID, requested []dto.AdImageRequest, knownAddresses map[string]bool) ([]adImage, bool) {
+	if len(requested) == 0 {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrMissingAdImage.Error(), nil)
+		return nil, false
+	}
+	if len(requested) > constants.MaxAdImages {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrTooManyAdImages.Error(), nil)
+		return nil, false
+	}
+
+	images := make([]adImage, len(requested))
+	for index, requestedImage := range requested {
+		switch {
+		case requestedImage.ImageID != "" && requestedImage.ImageAddress != "":
+			dto.ResponseWithError(c, http.StatusBadRequest, ErrAmbiguousAdImage.Error(), nil)
+			return nil, false
+		case requestedImage.ImageID != "":
+			image, err := cfg.getOwnedImage(c.Request.Context(), userID, requestedImage.ImageID)
+			if err != nil {
+				if errors.Is(err, ErrImageNotFound) {
+					dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+					return nil, false
+				}
+				dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+				return nil, false
+			}
+			images[index] = adImage{Address: imageURL(image.ID), ImageID: uuid.NullUUID{UUID: image.ID, Valid: true}}
+		case requestedImage.ImageAddress != "":
+			if !knownAddresses[requestedImage.ImageAddress] {
+				if err := cfg.validateImage(c.Request.Context(), requestedImage.ImageAddress); err != nil {
+					dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), err)
+					return nil, false
+				}
+			}
+			images[index] = adImage{Address: requestedImage.ImageAddress}
+		default:
+			dto.ResponseWithError(c, http.StatusBadRequest, ErrMissingAdImage.Error(), nil)
+			return nil, false
+		}
+	}
+	return images, true
+}
+
+// saveAdImages replaces the whole gallery of the ad keeping the given order.
+// It's meant to be called inside a transaction together with the cover update.
+func saveAdImages(ctx context.Context, db *database.Queries, adID uuid.UUID, images []adImage, coverIndex int) ([]database.AdImage, error) {
+	if err := db.DeleteAdImages(ctx, adID); err != nil {
+		return nil, err
+	}
+
+	gallery := make([]database.AdImage, len(images))
+	for position, image := range images {
+		savedImage, err := db.CreateAdImage(
+			ctx,
+			database.CreateAdImageParams{
+				AdID:         adID,
+				ImageAddress: image.Address,
+				ImageID:      image.ImageID,
+				Position:     int32(position),
+				IsCover:      position == coverIndex,
+				CreatedAt:    time.Now().UTC(),
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		gallery[position] = savedImage
+	}
+	return gallery, nil
+}
+
+func adImagesResponse(gallery []database.AdImage) []dto.AdImageResponse {
+	response := make([]dto.AdImageResponse, len(gallery))
+	for index, image := range gallery {
+		var imageID string
+		if image.ImageID.Valid {
+			imageID = image.ImageID.UUID.String()
+		}
+		response[index] = dto.AdImageResponse{
+			ID:           image.ID.String(),
+			ImageAddress: image.ImageAddress,
+			ImageID:      imageID,
+			Position:     int(image.Position),
+			IsCover:      image.IsCover,
+		}
+	}
+	return response
+}
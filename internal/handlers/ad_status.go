@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+)
+
+const (
+	AdStatusDraft     = "draft"
+	AdStatusPublished = "published"
+	AdStatusReserved  = "reserved"
+	AdStatusSold      = "sold"
+	AdStatusArchived  = "archived"
+)
+
+var (
+	ErrInvalidAdStatus           = errors.New("invalid advertisement status")
+	ErrForbiddenStatusTransition = errors.New("advertisement can't be moved to this status")
+	ErrAdStatusChanged           = errors.New("advertisement status was changed by another request")
+	ErrAdChanged                 = errors.New("advertisement was changed by another request")
+)
+
+// adStatusTransitions describes the ad lifecycle: draft -> published -> reserved -> sold/archived.
+// Reversals are allowed where they make sense, e.g. a reservation can fall through
+// and a published ad can be taken back to draft.
+var adStatusTransitions = map[string][]string{
+	AdStatusDraft:     {AdStatusPublished, AdStatusArchived},
+	AdStatusPublished: {AdStatusDraft, AdStatusReserved, AdStatusSold, AdStatusArchived},
+	AdStatusReserved:  {AdStatusPublished, AdStatusSold, AdStatusArchived},
+	AdStatusSold:      {AdStatusArchived},
+	AdStatusArchived:  {AdStatusDraft, AdStatusPublished},
+}
+
+func validateAdStatus(status string) error {
+	if _, ok := adStatusTransitions[status]; !ok {
+		return ErrInvalidAdStatus
+	}
+	return nil
+}
+
+func validateAdStatusTransition(from, to string) error {
+	if err := validateAdStatus(to); err != nil {
+		return err
+	}
+	if !slices.Contains(adStatusTransitions[from], to) {
+		return ErrForbiddenStatusTransition
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAdStatusTransition(t *testing.T) {
+	tests := map[string]struct {
+		from    string
+		to      string
+		wantErr error
+	}{
+		"draft_to_published":    {from: AdStatusDraft, to: AdStatusPublished, wantErr: nil},
+		"published_to_reserved": {from: AdStatusPublished, to: AdStatusReserved, wantErr: nil},
+		"reserved_to_published": {from: AdStatusReserved, to: AdStatusPublished, wantErr: nil},
+		"reserved_to_sold":      {from: AdStatusReserved, to: AdStatusSold, wantErr: nil},
+		"sold_to_archived":      {from: AdStatusSold, to: AdStatusArchived, wantErr: nil},
+		"draft_to_sold":         {from: AdStatusDraft, to: AdStatusSold, wantErr: ErrForbiddenStatusTransition},
+		"sold_to_published":     {from: AdStatusSold, to: AdStatusPublished, wantErr: ErrForbiddenStatusTransition},
+		"same_status":           {from: AdStatusPublished, to: AdStatusPublished, wantErr: ErrForbiddenStatusTransition},
+		"unknown_status":        {from: AdStatusPublished, to: "deleted", wantErr: ErrInvalidAdStatus},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateAdStatusTransition(tc.from, tc.to)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+		})
+	}
+}
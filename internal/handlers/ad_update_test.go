@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+)
+
+func TestApplyAdUpdate(t *testing.T) {
+	emptyTitle := ""
+	newTitle := "Macbook Air 13 M2"
+	shortDescription := "desc"
+	negativePrice := -1
+	newPrice := 65000
+
+	tests := map[string]struct {
+		input     dto.UpdateAdsRequest
+		wantErr   error
+		wantTitle string
+		wantPrice int32
+	}{
+		"nothing_to_update":          {input: dto.UpdateAdsRequest{}, wantErr: ErrNothingToUpdate},
+		"invalid_length_title":       {input: dto.UpdateAdsRequest{Title: &emptyTitle}, wantErr: ErrInvalidLengthTitle},
+		"invalid_length_description": {input: dto.UpdateAdsRequest{Description: &shortDescription}, wantErr: ErrInvalidLengthDescription},
+		"invalid_price":              {input: dto.UpdateAdsRequest{Price: &negativePrice}, wantErr: ErrInvalidPrice},
+		"valid_title_and_price":      {input: dto.UpdateAdsRequest{Title: &newTitle, Price: &newPrice}, wantErr: nil, wantTitle: newTitle, wantPrice: 65000},
+		"valid_price_only":           {input: dto.UpdateAdsRequest{Price: &newPrice}, wantErr: nil, wantTitle: "Macbook Air 13 M1", wantPrice: 65000},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			params := database.UpdateAdvertisementParams{
+				Title:        "Macbook Air 13 M1",
+				Description:  "Super cool and brand new laptop (2020 lol)",
+				ImageAddress: "https://iili.io/2g8pbwu.jpg",
+				Price:        78900,
+			}
+			err := applyAdUpdate(&params, tc.input)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if params.Title != tc.wantTitle || params.Price != tc.wantPrice {
+				t.Fatalf("%s: expected title %q and price %d, got %q and %d", name, tc.wantTitle, tc.wantPrice, params.Title, params.Price)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	passwordvalidator "github.com/wagslane/go-password-validator"
+)
+
+var (
+	ErrAdminPasswordMismatch = errors.New("password of the existing user doesn't match the admin password")
+	ErrAdminDeleted          = errors.New("existing user is deleted")
+)
+
+// BootstrapAdmin makes sure there is an admin to assign the other roles. It does nothing when
+// an admin already exists, otherwise the user with the login is created with the password.
+// An existing user is only promoted if the password is theirs: whoever registered the login
+// mustn't become an admin just because it's configured here.
+func (cfg *ApiConfig) BootstrapAdmin(ctx context.Context, login, password string) error {
+	admins, err := cfg.DB.CountUsersWithRole(ctx, auth.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	user, err := cfg.DB.GetUserByLogin(ctx, login)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if err := validateLogin(login); err != nil {
+			return err
+		}
+		if err := passwordvalidator.Validate(password, constants.MinEntropyBits); err != nil {
+			return fmt.Errorf("admin password: %w", err)
+		}
+		hashedPassword, err := auth.HashPassword(password)
+		if err != nil {
+			return err
+		}
+		_, err = cfg.DB.CreateUser(
+			ctx,
+			database.CreateUserParams{
+				Login:          login,
+				HashedPassword: hashedPassword,
+				CreatedAt:      time.Now().UTC(),
+				UpdatedAt:      time.Now().UTC(),
+			},
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("created admin user %s", login)
+	case err != nil:
+		return err
+	case user.DeletedAt.Valid:
+		return ErrAdminDeleted
+	case auth.CheckPasswordHash(password, user.HashedPassword) != nil:
+		return ErrAdminPasswordMismatch
+	}
+
+	_, err = cfg.DB.UpdateUserRole(
+		ctx,
+		database.UpdateUserRoleParams{
+			Login:     login,
+			Role:      auth.RoleAdmin,
+			UpdatedAt: time.Now().UTC(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("user %s is an admin now", login)
+	return nil
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+)
+
+func TestBuildCategoryTree(t *testing.T) {
+	categories := []database.Category{
+		{ID: 1, Name: "Электроника", Slug: "electronics"},
+		{ID: 2, Name: "Транспорт", Slug: "transport"},
+		{ID: 3, ParentID: sql.NullInt32{Int32: 1, Valid: true}, Name: "Телефоны", Slug: "phones"},
+		{ID: 4, ParentID: sql.NullInt32{Int32: 3, Valid: true}, Name: "Смартфоны", Slug: "smartphones"},
+		{ID: 5, ParentID: sql.NullInt32{Int32: 2, Valid: true}, Name: "Автомобили", Slug: "cars"},
+	}
+
+	tree := buildCategoryTree(categories)
+	if len(tree) != 2 {
+		t.Fatalf("expected 2 root categories, got %d", len(tree))
+	}
+
+	electronics := tree[0]
+	if electronics.Slug != "electronics" || len(electronics.Children) != 1 {
+		t.Fatalf("expected electronics with 1 child, got %+v", electronics)
+	}
+	phones := electronics.Children[0]
+	if phones.Slug != "phones" || len(phones.Children) != 1 || phones.Children[0].Slug != "smartphones" {
+		t.Fatalf("expected phones with smartphones child, got %+v", phones)
+	}
+	if len(phones.Children[0].Children) != 0 || phones.Children[0].Children == nil {
+		t.Fatalf("expected leaf category to have empty children list, got %+v", phones.Children[0].Children)
+	}
+
+	transport := tree[1]
+	if transport.Slug != "transport" || len(transport.Children) != 1 || transport.Children[0].Slug != "cars" {
+		t.Fatalf("expected transport with cars child, got %+v", transport)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// adsCursor points at the last ad of a feed page. It is bound to the sorting and the filters
+// it was issued for, so it can't be replayed with a different `sort_by`/`order` or search.
+type adsCursor struct {
+	SortBy  string    `json:"s"`
+	Order   string    `json:"o"`
+	Filters string    `json:"f"`
+	Value   string    `json:"v"`
+	ID      uuid.UUID `json:"id"`
+}
+
+// encodeCursor turns the cursor into an opaque `<payload>.<signature>` string
+// so clients can't forge positions they've never been given.
+func encodeCursor(cursor adsCursor, secret string) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	signature := base64.RawURLEncoding.EncodeToString(signCursor(encodedPayload, secret))
+	return encodedPayload + "." + signature, nil
+}
+
+func decodeCursor(rawCursor, secret string) (adsCursor, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(rawCursor, ".")
+	if !found {
+		return adsCursor{}, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return adsCursor{}, ErrInvalidCursor
+	}
+	if !hmac.Equal(signature, signCursor(encodedPayload, secret)) {
+		return adsCursor{}, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return adsCursor{}, ErrInvalidCursor
+	}
+	cursor := adsCursor{}
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return adsCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func signCursor(encodedPayload, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("ads-cursor:" + encodedPayload))
+	return mac.Sum(nil)
+}
+
+// adsFilters fingerprints the filters of the feed query: a position in one feed means nothing
+// in another, e.g. a price cursor from a search would skip ads of the whole catalogue.
+func adsFilters(params database.GetAdvertisementsParams) string {
+	filters, _ := json.Marshal([]any{
+		params.Query.String,
+		params.Query.Valid,
+		params.CategoryID.Int32,
+		params.CategoryID.Valid,
+		params.MinPrice,
+		params.MaxPrice,
+		params.Status,
+		params.UserID.UUID,
+		params.UserID.Valid,
+		params.IncludeBroken,
+	})
+	sum := sha256.Sum256(filters)
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// cursorFromRow builds the cursor that continues the feed right after the given ad.
+func cursorFromRow(ad database.GetAdvertisementsRow, sortBy, order, filters string) adsCursor {
+	cursor := adsCursor{SortBy: sortBy, Order: order, Filters: filters, ID: ad.ID}
+	switch sortBy {
+	case "price":
+		cursor.Value = strconv.Itoa(int(ad.Price))
+	case "relevance":
+		cursor.Value = strconv.FormatFloat(float64(ad.SearchRank), 'g', -1, 32)
+	default:
+		cursor.Value = ad.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+// applyCursor fills the keyset part of the feed query with the position stored in the cursor.
+func applyCursor(params *database.GetAdvertisementsParams, cursor adsCursor) error {
+	switch cursor.SortBy {
+	case "price":
+		price, err := strconv.Atoi(cursor.Value)
+		if err != nil {
+			return ErrInvalidCursor
+		}
+		params.CursorPrice = sql.NullInt32{Int32: int32(price), Valid: true}
+	case "relevance":
+		rank, err := strconv.ParseFloat(cursor.Value, 32)
+		if err != nil {
+			return ErrInvalidCursor
+		}
+		params.CursorRank = sql.NullFloat64{Float64: rank, Valid: true}
+	case "created_at":
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return ErrInvalidCursor
+		}
+		params.CursorCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+	default:
+		return ErrInvalidCursor
+	}
+
+	params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	params.Offset = 0
+	return nil
+}
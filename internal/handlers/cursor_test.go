@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	secret := "test-secret"
+	createdAt := time.Date(2025, 7, 14, 10, 30, 15, 123456000, time.UTC)
+	ad := database.GetAdvertisementsRow{
+		ID:         uuid.New(),
+		Price:      78900,
+		CreatedAt:  createdAt,
+		SearchRank: 0.0607927,
+	}
+
+	tests := map[string]struct {
+		sortBy string
+		order  string
+		check  func(params database.GetAdvertisementsParams) bool
+	}{
+		"price_asc": {sortBy: "price", order: "asc", check: func(params database.GetAdvertisementsParams) bool {
+			return params.CursorPrice.Valid && params.CursorPrice.Int32 == ad.Price
+		}},
+		"created_at_desc": {sortBy: "created_at", order: "desc", check: func(params database.GetAdvertisementsParams) bool {
+			return params.CursorCreatedAt.Valid && params.CursorCreatedAt.Time.Equal(createdAt)
+		}},
+		"relevance_desc": {sortBy: "relevance", order: "desc", check: func(params database.GetAdvertisementsParams) bool {
+			return params.CursorRank.Valid && float32(params.CursorRank.Float64) == ad.SearchRank
+		}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := encodeCursor(cursorFromRow(ad, tc.sortBy, tc.order, "filters"), secret)
+			if err != nil {
+				t.Fatalf("%s: couldn't encode cursor: %v", name, err)
+			}
+
+			cursor, err := decodeCursor(encoded, secret)
+			if err != nil {
+				t.Fatalf("%s: couldn't decode cursor: %v", name, err)
+			}
+			if cursor.SortBy != tc.sortBy || cursor.Order != tc.order || cursor.Filters != "filters" || cursor.ID != ad.ID {
+				t.Fatalf("%s: cursor doesn't match, got %+v", name, cursor)
+			}
+
+			params := database.GetAdvertisementsParams{Offset: 50}
+			if err := applyCursor(&params, cursor); err != nil {
+				t.Fatalf("%s: couldn't apply cursor: %v", name, err)
+			}
+			if !tc.check(params) || params.CursorID.UUID != ad.ID || params.Offset != 0 {
+				t.Fatalf("%s: unexpected params: %+v", name, params)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsForgery(t *testing.T) {
+	encoded, err := encodeCursor(adsCursor{SortBy: "price", Order: "asc", Value: "100", ID: uuid.New()}, "test-secret")
+	if err != nil {
+		t.Fatalf("couldn't encode cursor: %v", err)
+	}
+
+	tests := map[string]struct {
+		cursor string
+		secret string
+	}{
+		"wrong_secret":     {cursor: encoded, secret: "another-secret"},
+		"tampered_payload": {cursor: "x" + encoded, secret: "test-secret"},
+		"no_signature":     {cursor: "eyJzIjoicHJpY2UifQ", secret: "test-secret"},
+		"garbage":          {cursor: "not.a-cursor", secret: "test-secret"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeCursor(tc.cursor, tc.secret)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("%s: expected: %v, got: %v", name, ErrInvalidCursor, err)
+			}
+		})
+	}
+}
+
+func TestAdsFilters(t *testing.T) {
+	base := database.GetAdvertisementsParams{
+		Query:      sql.NullString{String: "iphone", Valid: true},
+		MinPrice:   0,
+		MaxPrice:   100000,
+		Status:     AdStatusPublished,
+		CategoryID: sql.NullInt32{Int32: 3, Valid: true},
+	}
+	userID := uuid.New()
+
+	tests := map[string]struct {
+		change    func(params *database.GetAdvertisementsParams)
+		wantEqual bool
+	}{
+		"same_filters": {change: func(params *database.GetAdvertisementsParams) {}, wantEqual: true},
+		"position_is_ignored": {change: func(params *database.GetAdvertisementsParams) {
+			params.Limit = 26
+			params.CursorID = uuid.NullUUID{UUID: userID, Valid: true}
+			params.CursorPrice = sql.NullInt32{Int32: 500, Valid: true}
+			params.OrderBy = "price"
+		}, wantEqual: true},
+		"other_query": {change: func(params *database.GetAdvertisementsParams) {
+			params.Query.String = "ipad"
+		}},
+		"no_query": {change: func(params *database.GetAdvertisementsParams) {
+			params.Query = sql.NullString{}
+		}},
+		"other_category": {change: func(params *database.GetAdvertisementsParams) {
+			params.CategoryID.Int32 = 4
+		}},
+		"no_category": {change: func(params *database.GetAdvertisementsParams) {
+			params.CategoryID = sql.NullInt32{}
+		}},
+		"other_min_price": {change: func(params *database.GetAdvertisementsParams) {
+			params.MinPrice = 100
+		}},
+		"other_max_price": {change: func(params *database.GetAdvertisementsParams) {
+			params.MaxPrice = 500
+		}},
+		"other_status": {change: func(params *database.GetAdvertisementsParams) {
+			params.Status = AdStatusSold
+		}},
+		"own_ads": {change: func(params *database.GetAdvertisementsParams) {
+			params.UserID = uuid.NullUUID{UUID: userID, Valid: true}
+		}},
+		"with_broken": {change: func(params *database.GetAdvertisementsParams) {
+			params.IncludeBroken = true
+		}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			params := base
+			tc.change(&params)
+			got := adsFilters(params) == adsFilters(base)
+			if got != tc.wantEqual {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantEqual, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	DataExportPending    = "pending"
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+)
+
+// RunDataExporter builds requested data exports and deletes expired ones until ctx is cancelled.
+func (cfg *ApiConfig) RunDataExporter(ctx context.Context) {
+	ticker := time.NewTicker(constants.ExportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := cfg.processDataExports(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("couldn't process data exports: %v", err)
+		}
+		if err := cfg.deleteExpiredDataExports(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			log.Printf("couldn't delete expired data exports: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processDataExports builds pending exports one by one. Exports left in processing by
+// an instance that stopped halfway are picked up again once they're stale.
+func (cfg *ApiConfig) processDataExports(ctx context.Context) error {
+	for {
+		now := time.Now().UTC()
+		export, err := cfg.DB.ClaimDataExport(
+			ctx,
+			database.ClaimDataExportParams{
+				StartedAt:   sql.NullTime{Time: now, Valid: true},
+				StaleBefore: now.Add(-constants.ExportStaleAfter),
+			},
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		storageKey := "exports/" + export.ID.String() + ".zip"
+		size, buildErr := cfg.buildDataExport(ctx, storageKey, export.UserID)
+		if ctx.Err() != nil {
+			// shutting down, the export will be picked up again
+			return ctx.Err()
+		}
+
+		completedAt := time.Now().UTC()
+		if buildErr != nil {
+			log.Printf("couldn't build data export %s: %v", export.ID, buildErr)
+			err = cfg.DB.FailDataExport(
+				ctx,
+				database.FailDataExportParams{
+					ID:          export.ID,
+					Error:       sql.NullString{String: buildErr.Error(), Valid: true},
+					CompletedAt: sql.NullTime{Time: completedAt, Valid: true},
+					ExpiresAt:   sql.NullTime{Time: completedAt.Add(constants.ExportLifetime), Valid: true},
+				},
+			)
+		} else {
+			err = cfg.DB.CompleteDataExport(
+				ctx,
+				database.CompleteDataExportParams{
+					ID:          export.ID,
+					StorageKey:  sql.NullString{String: storageKey, Valid: true},
+					Size:        sql.NullInt64{Int64: size, Valid: true},
+					CompletedAt: sql.NullTime{Time: completedAt, Valid: true},
+					ExpiresAt:   sql.NullTime{Time: completedAt.Add(constants.ExportLifetime), Valid: true},
+				},
+			)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// buildDataExport streams the archive right into the storage and returns its size.
+func (cfg *ApiConfig) buildDataExport(ctx context.Context, storageKey string, userID uuid.UUID) (int64, error) {
+	reader, writer := io.Pipe()
+	counter := &countingWriter{w: writer}
+	go func() {
+		writer.CloseWithError(cfg.writeDataExport(ctx, counter, userID))
+	}()
+
+	err := cfg.Storage.Put(ctx, storageKey, reader)
+	// stops the archive writer if the storage gave up halfway
+	reader.CloseWithError(err)
+	if err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// writeDataExport writes a ZIP archive with everything stored about the user: JSON files
+// with the records and the original uploaded images.
+func (cfg *ApiConfig) writeDataExport(ctx context.Context, w io.Writer, userID uuid.UUID) error {
+	archive := zip.NewWriter(w)
+
+	user, err := cfg.DB.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// an export requested right before the deletion would hold whatever is left of the account
+	if user.DeletedAt.Valid {
+		return ErrUserNotFound
+	}
+	exportUser := dto.ExportUser{
+		ID:                 user.ID,
+		Login:              user.Login,
+		Role:               user.Role,
+		DisplayName:        user.DisplayName.String,
+		AvatarImageID:      nullUUIDPtr(user.AvatarImageID),
+		Bio:                user.Bio.String,
+		City:               user.City.String,
+		ContactPreferences: user.ContactPreferences,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+		TokensValidAfter:   nullTimePtr(user.TokensValidAfter),
+	}
+	if err := writeJSONFile(archive, "user.json", exportUser); err != nil {
+		return err
+	}
+
+	ads, err := cfg.DB.GetUserAdvertisements(ctx, userID)
+	if err != nil {
+		return err
+	}
+	exportAds := make([]dto.ExportAdvertisement, len(ads))
+	for index, ad := range ads {
+		gallery, err := cfg.DB.GetAdImages(ctx, ad.ID)
+		if err != nil {
+			return err
+		}
+		exportAdImages := make([]dto.ExportAdImage, len(gallery))
+		for imageIndex, image := range gallery {
+			exportAdImages[imageIndex] = dto.ExportAdImage{
+				ID:           image.ID,
+				ImageAddress: image.ImageAddress,
+				ImageID:      nullUUIDPtr(image.ImageID),
+				Position:     int(image.Position),
+				IsCover:      image.IsCover,
+				CreatedAt:    image.CreatedAt,
+			}
+		}
+		exportAds[index] = dto.ExportAdvertisement{
+			ID:              ad.ID,
+			Title:           ad.Title,
+			Description:     ad.Description,
+			ImageAddress:    ad.ImageAddress,
+			ImageID:         nullUUIDPtr(ad.ImageID),
+			Price:           int(ad.Price),
+			CategoryID:      int(ad.CategoryID),
+			Status:          ad.Status,
+			CreatedAt:       ad.CreatedAt,
+			UpdatedAt:       ad.UpdatedAt,
+			ImageCheckedAt:  nullTimePtr(ad.ImageCheckedAt),
+			ImageCheckError: ad.ImageCheckError.String,
+			ImageBroken:     ad.ImageBroken,
+			Images:          exportAdImages,
+		}
+	}
+	if err := writeJSONFile(archive, "advertisements.json", exportAds); err != nil {
+		return err
+	}
+
+	images, err := cfg.DB.GetUserImages(ctx, userID)
+	if err != nil {
+		return err
+	}
+	exportImages := make([]dto.ExportImage, len(images))
+	for index, image := range images {
+		exportImages[index] = dto.ExportImage{
+			ID:          image.ID,
+			ContentType: image.ContentType,
+			Size:        int(image.Size),
+			CreatedAt:   image.CreatedAt,
+		}
+		// resized copies can be made from the original, so only the original is exported
+		fileName := "images/" + image.ID.String() + imageExtensions[image.ContentType]
+		written, err := cfg.copyStoredFile(ctx, archive, fileName, image.StorageKey)
+		if err != nil {
+			return err
+		}
+		if written {
+			exportImages[index].File = fileName
+		}
+	}
+	if err := writeJSONFile(archive, "images.json", exportImages); err != nil {
+		return err
+	}
+
+	refreshTokens, err := cfg.DB.GetUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+	exportSessions := make([]dto.ExportSession, len(refreshTokens))
+	for index, token := range refreshTokens {
+		exportSessions[index] = dto.ExportSession{
+			ID:        token.ID,
+			FamilyID:  token.FamilyID,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    nullTimePtr(token.UsedAt),
+			RevokedAt: nullTimePtr(token.RevokedAt),
+		}
+	}
+	if err := writeJSONFile(archive, "sessions.json", exportSessions); err != nil {
+		return err
+	}
+
+	security, err := cfg.exportSecurity(ctx, user)
+	if err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "security.json", security); err != nil {
+		return err
+	}
+
+	dataExports, err := cfg.DB.GetUserDataExports(ctx, userID)
+	if err != nil {
+		return err
+	}
+	exportDataExports := make([]dto.ExportDataExport, len(dataExports))
+	for index, dataExport := range dataExports {
+		exportDataExports[index] = dto.ExportDataExport{
+			ID:          dataExport.ID,
+			Status:      dataExport.Status,
+			CreatedAt:   dataExport.CreatedAt,
+			CompletedAt: nullTimePtr(dataExport.CompletedAt),
+		}
+	}
+	if err := writeJSONFile(archive, "exports.json", exportDataExports); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// exportSecurity collects records about the user's second factor, password resets, revoked tokens
+// and failed logins.
+func (cfg *ApiConfig) exportSecurity(ctx context.Context, user database.User) (dto.ExportSecurity, error) {
+	userID := user.ID
+	security := dto.ExportSecurity{}
+
+	userTOTP, err := cfg.DB.GetUserTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return dto.ExportSecurity{}, err
+	}
+	if err == nil {
+		security.TwoFactor = &dto.ExportTwoFactor{
+			CreatedAt:   userTOTP.CreatedAt,
+			ConfirmedAt: nullTimePtr(userTOTP.ConfirmedAt),
+		}
+	}
+
+	recoveryCodes, err := cfg.DB.GetUserRecoveryCodes(ctx, userID)
+	if err != nil {
+		return dto.ExportSecurity{}, err
+	}
+	security.RecoveryCodes = make([]dto.ExportRecoveryCode, len(recoveryCodes))
+	for index, code := range recoveryCodes {
+		security.RecoveryCodes[index] = dto.ExportRecoveryCode{
+			CreatedAt: code.CreatedAt,
+			UsedAt:    nullTimePtr(code.UsedAt),
+		}
+	}
+
+	resetTokens, err := cfg.DB.GetUserPasswordResetTokens(ctx, userID)
+	if err != nil {
+		return dto.ExportSecurity{}, err
+	}
+	security.PasswordResets = make([]dto.ExportPasswordReset, len(resetTokens))
+	for index, token := range resetTokens {
+		security.PasswordResets[index] = dto.ExportPasswordReset{
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    nullTimePtr(token.UsedAt),
+		}
+	}
+
+	revokedTokens, err := cfg.DB.GetUserRevokedTokens(ctx, userID)
+	if err != nil {
+		return dto.ExportSecurity{}, err
+	}
+	security.RevokedTokens = make([]dto.ExportRevokedToken, len(revokedTokens))
+	for index, token := range revokedTokens {
+		security.RevokedTokens[index] = dto.ExportRevokedToken{
+			ID:        token.Jti,
+			ExpiresAt: token.ExpiresAt,
+			RevokedAt: token.RevokedAt,
+		}
+	}
+
+	// the counter is kept by login, it only exists after failures that didn't start over yet
+	loginFailures, err := cfg.LoginGuard.Failures(ctx, user.Login)
+	if err != nil {
+		return dto.ExportSecurity{}, err
+	}
+	if loginFailures.Failures > 0 {
+		security.LoginFailures = &dto.ExportLoginFailures{
+			Failures:      loginFailures.Failures,
+			LastFailureAt: loginFailures.LastFailure,
+		}
+	}
+
+	return security, nil
+}
+
+// copyStoredFile adds the stored object to the archive. Objects missing from the storage
+// are skipped, written is false then.
+func (cfg *ApiConfig) copyStoredFile(ctx context.Context, archive *zip.Writer, name, storageKey string) (bool, error) {
+	content, err := cfg.Storage.Get(ctx, storageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer content.Close()
+
+	file, err := archive.Create(name)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteExpiredDataExports removes archives nobody can download anymore, files go first
+// for the same reason as in purgeAccount.
+func (cfg *ApiConfig) deleteExpiredDataExports(ctx context.Context, now time.Time) error {
+	for {
+		exports, err := cfg.DB.GetExpiredDataExports(
+			ctx,
+			database.GetExpiredDataExportsParams{
+				ExpiresAt: sql.NullTime{Time: now, Valid: true},
+				Limit:     constants.ExportBatchSize,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, export := range exports {
+			if export.StorageKey.Valid {
+				if err := cfg.Storage.Delete(ctx, export.StorageKey.String); err != nil {
+					return err
+				}
+			}
+			if err := cfg.DB.DeleteDataExport(ctx, export.ID); err != nil {
+				return err
+			}
+		}
+		if len(exports) < constants.ExportBatchSize {
+			return nil
+		}
+	}
+}
+
+func writeJSONFile(archive *zip.Writer, name string, value any) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+func nullUUIDPtr(value uuid.NullUUID) *uuid.UUID {
+	if !value.Valid {
+		return nil
+	}
+	return &value.UUID
+}
+
+// countingWriter counts bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidExportLink = errors.New("invalid or expired download link")
+
+// exportDownloadURL returns a link to the export archive that works without a token until expiresAt,
+// so it can be opened right in the browser.
+func exportDownloadURL(exportID uuid.UUID, expiresAt time.Time, secret string) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", signExportLink(exportID.String(), expires, secret))
+	return "/api/exports/" + exportID.String() + "/download?" + query.Encode()
+}
+
+// verifyExportLink checks the parameters of the link made by exportDownloadURL.
+func verifyExportLink(rawExportID, expires, signature, secret string, now time.Time) error {
+	if !hmac.Equal([]byte(signature), []byte(signExportLink(rawExportID, expires, secret))) {
+		return ErrInvalidExportLink
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return ErrInvalidExportLink
+	}
+	return nil
+}
+
+func signExportLink(rawExportID, expires, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("data-export:" + rawExportID + ":" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
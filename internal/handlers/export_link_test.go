@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExportLink(t *testing.T) {
+	secret := "test-secret"
+	exportID := uuid.New()
+	now := time.Date(2025, 7, 14, 10, 30, 0, 0, time.UTC)
+
+	link, err := url.Parse(exportDownloadURL(exportID, now.Add(15*time.Minute), secret))
+	if err != nil {
+		t.Fatalf("couldn't parse link: %v", err)
+	}
+	if link.Path != "/api/exports/"+exportID.String()+"/download" {
+		t.Fatalf("expected: %v, got: %v", "/api/exports/"+exportID.String()+"/download", link.Path)
+	}
+	expires := link.Query().Get("expires")
+	signature := link.Query().Get("signature")
+
+	tests := map[string]struct {
+		exportID  string
+		expires   string
+		signature string
+		secret    string
+		now       time.Time
+		wantErr   error
+	}{
+		"valid_link":         {exportID: exportID.String(), expires: expires, signature: signature, secret: secret, now: now, wantErr: nil},
+		"link_expired":       {exportID: exportID.String(), expires: expires, signature: signature, secret: secret, now: now.Add(15 * time.Minute), wantErr: ErrInvalidExportLink},
+		"other_export":       {exportID: uuid.NewString(), expires: expires, signature: signature, secret: secret, now: now, wantErr: ErrInvalidExportLink},
+		"expires_extended":   {exportID: exportID.String(), expires: expires + "0", signature: signature, secret: secret, now: now, wantErr: ErrInvalidExportLink},
+		"signature_tampered": {exportID: exportID.String(), expires: expires, signature: strings.ToUpper(signature), secret: secret, now: now, wantErr: ErrInvalidExportLink},
+		"signature_missing":  {exportID: exportID.String(), expires: expires, signature: "", secret: secret, now: now, wantErr: ErrInvalidExportLink},
+		"other_secret":       {exportID: exportID.String(), expires: expires, signature: signature, secret: "other-secret", now: now, wantErr: ErrInvalidExportLink},
+		"expires_not_number": {exportID: exportID.String(), expires: "tomorrow", signature: signExportLink(exportID.String(), "tomorrow", secret), secret: secret, now: now, wantErr: ErrInvalidExportLink},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := verifyExportLink(tc.exportID, tc.expires, tc.signature, tc.secret, tc.now)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+		})
+	}
+}
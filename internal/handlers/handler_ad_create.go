@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -12,19 +17,27 @@ import (
 	"github.com/englandrecoil/go-marketplace-service/internal/constants"
 	"github.com/englandrecoil/go-marketplace-service/internal/database"
 	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/fetcher"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecheck"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
 var (
 	ErrInvalidLengthTitle       = errors.New("invalid length of title")
 	ErrInvalidLengthDescription = errors.New("invalid length of description")
 	ErrInvalidPrice             = errors.New("incorrect price")
+	ErrInvalidInitialAdStatus   = errors.New("new advertisement can only be a draft or published")
+	ErrMissingAdImage           = errors.New("image_address or image_id is required")
+	ErrAmbiguousAdImage         = errors.New("only one of image_address and image_id can be set")
+	ErrImageTypeMismatch        = errors.New("image content doesn't match its content type")
+	ErrUnknownImageSize         = errors.New("couldn't determine image size")
 )
 
 // HandlerCreateAd godoc
 //
 //	@Summary		Создать новое объявление
-//	@Description	Создаёт новое объявление с заданными параметрами
+//	@Description	Создаёт новое объявление с заданными параметрами. Объявление может содержать до 10 изображений (`images`), каждое задаётся либо ссылкой `image_address`, либо `image_id` ранее загруженного изображения. Первое изображение становится обложкой. Поля `image_address`/`image_id` верхнего уровня поддерживаются для совместимости и добавляют изображение в начало галереи. Новое объявление может быть черновиком (`draft`) или сразу опубликованным (`published`, по умолчанию).
 //	@Accept			json
 //	@Produce		json
 //	@Security		BearerAuth
@@ -36,52 +49,81 @@ var (
 //	@Failure		500				{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
 //	@Router			/api/ads [post]
 func (cfg *ApiConfig) HandlerCreateAd(c *gin.Context) {
-	token, err := auth.GetBearerToken(c)
-	if err != nil {
-		dto.ResponseWithError(c, http.StatusUnauthorized, err.Error(), err)
+	userID := auth.MustUserID(c)
+
+	inputAdParams := dto.CreateAdsRequest{}
+	if err := c.BindJSON(&inputAdParams); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.Secret)
-	if err != nil {
-		dto.ResponseWithError(c, http.StatusUnauthorized, "invalid or expired access token", err)
+
+	if err := validateAdDetails(inputAdParams.Title, inputAdParams.Description, inputAdParams.Price); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if inputAdParams.Status == "" {
+		inputAdParams.Status = AdStatusPublished
+	}
+	if inputAdParams.Status != AdStatusDraft && inputAdParams.Status != AdStatusPublished {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidInitialAdStatus.Error(), nil)
 		return
 	}
 
-	inputAdParams := dto.CreateAdsRequest{}
-	if err := c.BindJSON(&inputAdParams); err != nil {
-		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+	// single image fields are still accepted and become the first image of the gallery
+	requestedImages := inputAdParams.Images
+	if inputAdParams.ImageAddress != "" || inputAdParams.ImageID != "" {
+		legacyImage := dto.AdImageRequest{ImageAddress: inputAdParams.ImageAddress, ImageID: inputAdParams.ImageID}
+		requestedImages = append([]dto.AdImageRequest{legacyImage}, requestedImages...)
+	}
+	images, ok := cfg.resolveAdImages(c, userID, requestedImages, nil)
+	if !ok {
 		return
 	}
 
-	// validation part
-	err = validateAdParams(
-		inputAdParams.Title,
-		inputAdParams.Description,
-		inputAdParams.ImageAddress,
-		inputAdParams.Price,
-	)
+	// ad and its gallery are created together
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
 	if err != nil {
-		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), err)
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
 
-	// create new record of ad in db
-	ad, err := cfg.DB.CreateAdvertisement(
+	// create new record of ad in db, the first image is the cover
+	ad, err := qtx.CreateAdvertisement(
 		c.Request.Context(),
 		database.CreateAdvertisementParams{
 			Title:        inputAdParams.Title,
 			Description:  inputAdParams.Description,
-			ImageAddress: inputAdParams.ImageAddress,
+			ImageAddress: images[0].Address,
 			Price:        int32(inputAdParams.Price),
 			CreatedAt:    time.Now().UTC(),
 			UpdatedAt:    time.Now().UTC(),
 			UserID:       userID,
+			Status:       inputAdParams.Status,
+			CategoryID:   int32(inputAdParams.CategoryID),
+			ImageID:      images[0].ImageID,
 		},
 	)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23503" {
+				dto.ResponseWithError(c, http.StatusBadRequest, "category not found", nil)
+				return
+			}
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	gallery, err := saveAdImages(c.Request.Context(), qtx, ad.ID, images, 0)
 	if err != nil {
 		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
 
 	c.JSON(
 		http.StatusCreated,
@@ -91,49 +133,116 @@ func (cfg *ApiConfig) HandlerCreateAd(c *gin.Context) {
 			Description:  ad.Description,
 			ImageAddress: ad.ImageAddress,
 			Price:        int(ad.Price),
+			CategoryID:   int(ad.CategoryID),
+			Status:       ad.Status,
 			CreatedAt:    ad.CreatedAt,
+			Images:       adImagesResponse(gallery),
 		},
 	)
 }
 
-func validateAdParams(title, description, imageUrl string, price int) error {
+// validateAdDetails checks everything about the ad except its image.
+func validateAdDetails(title, description string, price int) error {
+	if err := validateTitle(title); err != nil {
+		return err
+	}
+	if err := validateDescription(description); err != nil {
+		return err
+	}
+	if err := validatePrice(price); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateTitle(title string) error {
 	if utf8.RuneCountInString(title) < constants.MinTitleLength || utf8.RuneCountInString(title) > constants.MaxTitleLength {
 		return ErrInvalidLengthTitle
 	}
+	return nil
+}
+
+func validateDescription(description string) error {
 	if utf8.RuneCountInString(description) < constants.MinDescLength || utf8.RuneCountInString(description) > constants.MaxDescLength {
 		return ErrInvalidLengthDescription
 	}
+	return nil
+}
+
+func validatePrice(price int) error {
 	if price < constants.MinPrice || price > constants.MaxPrice {
 		return ErrInvalidPrice
 	}
-	if err := validateImage(imageUrl); err != nil {
-		return err
-	}
 	return nil
 }
 
-func validateImage(imageUrl string) error {
+// validateImage downloads the beginning of the remote image and checks it the same way as uploads.
+// The request goes through cfg.Fetcher, so user supplied URLs can't be used to probe the internal network.
+func (cfg *ApiConfig) validateImage(ctx context.Context, imageUrl string) error {
 	if _, err := url.ParseRequestURI(imageUrl); err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("HEAD", imageUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageUrl, nil)
 	if err != nil {
 		return fmt.Errorf("couldn't get image metadata: %s", err)
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	// the header is all we need, servers supporting ranges won't send the rest
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", constants.ImageProbeSize-1))
+
+	res, err := cfg.Fetcher.Do(req)
 	if err != nil {
+		for _, fetchErr := range []error{fetcher.ErrForbiddenAddress, fetcher.ErrUnsupportedScheme, fetcher.ErrTooManyRedirects} {
+			if errors.Is(err, fetchErr) {
+				return fetchErr
+			}
+		}
 		return fmt.Errorf("can't send request to server: %s", err)
 	}
 	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("image server responded with status %d", res.StatusCode)
+	}
 
-	imageType := res.Header.Get("content-type")
-	if imageType != "image/jpeg" && imageType != "image/jpg" && imageType != "image/png" {
-		return errors.New("invalid image format")
+	prefix, err := io.ReadAll(io.LimitReader(res.Body, constants.ImageProbeSize))
+	if err != nil {
+		return fmt.Errorf("couldn't download image: %s", err)
 	}
-	if res.ContentLength > constants.MaxImageSize {
-		return errors.New("image size is too big")
+	info, err := cfg.ImagePolicy.Check(bytes.NewReader(prefix))
+	if err != nil {
+		return err
+	}
+	if !imagecheck.ContentTypeMatches(res.Header.Get("Content-Type"), info) {
+		return ErrImageTypeMismatch
+	}
+
+	size, err := remoteImageSize(res, int64(len(prefix)))
+	if err != nil {
+		return err
+	}
+	if size > constants.MaxImageSize {
+		return ErrImageTooBig
 	}
 	return nil
 }
+
+// remoteImageSize finds out the full size of the remote image. Content-Length may be missing or wrong,
+// so unless the server answered with a range and told the total, the rest of the body is counted.
+func remoteImageSize(res *http.Response, prefixSize int64) (int64, error) {
+	if res.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes 0-131071/2097152
+		_, total, _ := strings.Cut(res.Header.Get("Content-Range"), "/")
+		size, err := strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			return 0, ErrUnknownImageSize
+		}
+		return size, nil
+	}
+
+	// reading one byte over the limit is enough to tell the image is too big
+	rest, err := io.Copy(io.Discard, io.LimitReader(res.Body, constants.MaxImageSize+1-prefixSize))
+	if err != nil {
+		return 0, fmt.Errorf("couldn't download image: %s", err)
+	}
+	return prefixSize + rest, nil
+}
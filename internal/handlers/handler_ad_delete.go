@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerDeleteAd godoc
+//
+//	@Summary		Удалить объявление
+//	@Description	Удаляет объявление. Доступно только автору объявления.
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header	string	true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			id				path	string	true	"ID объявления"	format(uuid)
+//	@Success		204				"Объявление удалено"
+//	@Failure		401				{object}	dto.ErrorResponse	"Невалидный или просроченный токен-доступа"
+//	@Failure		403				{object}	dto.ErrorResponse	"Объявление принадлежит другому пользователю"
+//	@Failure		404				{object}	dto.ErrorResponse	"Объявление не найдено"
+//	@Failure		500				{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/ads/{id} [delete]
+func (cfg *ApiConfig) HandlerDeleteAd(c *gin.Context) {
+	userID := auth.MustUserID(c)
+
+	ad, ok := cfg.getOwnedAd(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := cfg.DB.DeleteAdvertisement(c.Request.Context(), ad.ID); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
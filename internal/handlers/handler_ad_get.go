@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/englandrecoil/go-marketplace-service/internal/auth"
 	"github.com/englandrecoil/go-marketplace-service/internal/constants"
@@ -23,37 +25,31 @@ var (
 // HandlerGetAds godoc
 //
 //	@Summary		Получить объявления
-//	@Description	Позволяет получить объявления пользователей. Авторизованным пользователям доступно получение параметра `is_owner`.
+//	@Description	Позволяет получить опубликованные объявления пользователей. Авторизованным пользователям доступно получение параметра `is_owner`, а также своих объявлений в любом статусе. В `image_address` возвращается обложка объявления, для загруженных изображений в `image_variants` также возвращаются ссылки на уменьшенные копии.
 //	@Produce		json
 //	@Param			Authorization	header		string				false	"Bearer токен"							example(Bearer J2bc3Cd0F...)
+//	@Param			q				query		string				false	"Поисковый запрос по заголовку и описанию"
 //	@Param			page			query		int					false	"Номер страницы"						default(1)	minimum(1)
+//	@Param			cursor			query		string				false	"Курсор next_cursor из предыдущего ответа, при его наличии page игнорируется. Курсор действует только с теми же фильтрами и сортировкой, с которыми был получен"
 //	@Param			page_size		query		int					false	"Количество возвращаемых объявлений"	default(25)	minimum(1)	maximum(100)
 //	@Param			min_price		query		int					false	"Минимальная цена"						minimum(0)
 //	@Param			max_price		query		int					false	"Максимальная цена"						maximum(99999999)
-//	@Param			sort_by			query		string				false	"Поле для сортировки"					default(created_at)	Enums(price, created_at)
+//	@Param			category		query		int					false	"ID категории, включая все её подкатегории"
+//	@Param			sort_by			query		string				false	"Поле для сортировки, relevance доступно только вместе с q"	default(created_at)	Enums(price, created_at, relevance)
 //	@Param			order			query		string				false	"Направление сортировки"				default(desc)		Enums(asc, desc)
-//	@Success		200				{array}		dto.GetAdsResponse	"Успешный ответ"
+//	@Param			status			query		string				false	"Статус объявлений, отличный от published доступен только для своих объявлений"	default(published)	Enums(draft, published, reserved, sold, archived)
+//	@Param			mine			query		bool				false	"Только свои объявления"
+//	@Param			with_total		query		bool				false	"Подсчитывать общее количество объявлений, false позволяет сэкономить на запросе"	default(true)
+//	@Success		200				{object}	dto.GetAdsListResponse	"Успешный ответ"
+//	@Header			200				{string}	Link					"Ссылки на соседние страницы (RFC 8288)"
+//	@Header			200				{string}	X-Next-Cursor			"Курсор следующей страницы, совпадает с next_cursor"
 //	@Failure		400				{object}	dto.ErrorResponse	"Неверные параметры запроса"
 //	@Failure		401				{object}	dto.ErrorResponse	"Невалидный или просроченный токен-доступа"
 //	@Failure		500				{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
 //	@Router			/api/ads [get]
 func (cfg *ApiConfig) HandlerGetAds(c *gin.Context) {
-	// if no auth header provided - simply exclude `is_owner` field from response in the future and do nothing lol
-	// if auth header is provided - validate it as usual
-	var userID uuid.UUID
-	authHeader := c.GetHeader("Authorization")
-	if authHeader != "" {
-		token, err := auth.GetBearerToken(c)
-		if err != nil {
-			dto.ResponseWithError(c, http.StatusUnauthorized, err.Error(), err)
-			return
-		}
-		userID, err = auth.ValidateJWT(token, cfg.Secret)
-		if err != nil {
-			dto.ResponseWithError(c, http.StatusUnauthorized, "invalid or expired access token", err)
-			return
-		}
-	}
+	// the route uses OptionalAuth: anonymous requests simply get no `is_owner` field in the response
+	userID, _ := auth.UserID(c)
 
 	query := dto.GetAdsQueryParamsRequest{}
 	if err := c.BindQuery(&query); err != nil {
@@ -62,18 +58,7 @@ func (cfg *ApiConfig) HandlerGetAds(c *gin.Context) {
 	}
 
 	// validate query params
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.PageSize <= 0 || query.PageSize > 100 {
-		query.PageSize = 25
-	}
-	if query.SortBy != "price" && query.SortBy != "created_at" {
-		query.SortBy = "created_at"
-	}
-	if query.Order != "asc" && query.Order != "desc" {
-		query.Order = "desc"
-	}
+	normalizeAdsPage(&query)
 	if query.MinPrice == nil {
 		defaultMinPrice := constants.MinPrice
 		query.MinPrice = &defaultMinPrice
@@ -86,43 +71,190 @@ func (cfg *ApiConfig) HandlerGetAds(c *gin.Context) {
 		dto.ResponseWithError(c, http.StatusBadRequest, "min_price cannot be greater than max_price", nil)
 		return
 	}
-	offset := (query.Page - 1) * query.PageSize
+	if query.Status == "" {
+		query.Status = AdStatusPublished
+	}
+	if err := validateAdStatus(query.Status); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var categoryID sql.NullInt32
+	if query.Category != nil {
+		categoryID = sql.NullInt32{Int32: int32(*query.Category), Valid: true}
+	}
+
+	// everyone sees published ads, other statuses are only listed for their owner
+	var authorID uuid.NullUUID
+	if query.Mine || query.Status != AdStatusPublished {
+		if userID == uuid.Nil {
+			dto.ResponseWithError(c, http.StatusUnauthorized, "authorization required to list your own advertisements", nil)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+
+	params := database.GetAdvertisementsParams{
+		MinPrice:      int32(*query.MinPrice),
+		MaxPrice:      int32(*query.MaxPrice),
+		Status:        query.Status,
+		UserID:        authorID,
+		IncludeBroken: authorID.Valid,
+		Query:         sql.NullString{String: query.Q, Valid: query.Q != ""},
+		CategoryID:    categoryID,
+	}
+	response, ok := cfg.listAds(c, query, params, userID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// normalizeAdsPage trims the search query and replaces missing or invalid paging and
+// sorting parameters with defaults.
+func normalizeAdsPage(query *dto.GetAdsQueryParamsRequest) {
+	query.Q = strings.TrimSpace(query.Q)
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 || query.PageSize > 100 {
+		query.PageSize = 25
+	}
+	if query.SortBy == "" && query.Q != "" {
+		query.SortBy = "relevance"
+	}
+	if query.SortBy != "price" && query.SortBy != "created_at" && (query.SortBy != "relevance" || query.Q == "") {
+		query.SortBy = "created_at"
+	}
+	if query.Order != "asc" && query.Order != "desc" {
+		query.Order = "desc"
+	}
+}
+
+// listAds returns the page of ads matching the filters in params, the page itself is taken from query.
+// It sets the `Link` header, on failure the error response is already written and ok is false.
+// viewerID is uuid.Nil for anonymous requests, they get no `is_owner` field.
+func (cfg *ApiConfig) listAds(c *gin.Context, query dto.GetAdsQueryParamsRequest, params database.GetAdvertisementsParams, viewerID uuid.UUID) (dto.GetAdsListResponse, bool) {
+	// one extra ad is requested to find out whether the next page exists
+	params.Limit = int32(query.PageSize + 1)
+	params.Offset = int32((query.Page - 1) * query.PageSize)
+	params.OrderBy = query.SortBy
+	params.OrderDir = query.Order
+
+	// cursor takes precedence over page: it points right after the last ad the client has seen
+	cursorMode := query.Cursor != ""
+	if cursorMode {
+		cursor, err := decodeCursor(query.Cursor, cfg.Secret)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+			return dto.GetAdsListResponse{}, false
+		}
+		if cursor.SortBy != query.SortBy || cursor.Order != query.Order {
+			dto.ResponseWithError(c, http.StatusBadRequest, "cursor doesn't match sort_by and order parameters", nil)
+			return dto.GetAdsListResponse{}, false
+		}
+		if cursor.Filters != adsFilters(params) {
+			dto.ResponseWithError(c, http.StatusBadRequest, "cursor doesn't match filter parameters", nil)
+			return dto.GetAdsListResponse{}, false
+		}
+		if err := applyCursor(&params, cursor); err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+			return dto.GetAdsListResponse{}, false
+		}
+	}
 
 	// get ads from db
-	dbAds, err := cfg.DB.GetAdvertisements(
-		c.Request.Context(),
-		database.GetAdvertisementsParams{
-			Limit:    int32(query.PageSize),
-			Offset:   int32(offset),
-			MinPrice: int32(*query.MinPrice),
-			MaxPrice: int32(*query.MaxPrice),
-			OrderBy:  query.SortBy,
-			OrderDir: query.Order,
-		},
-	)
+	dbAds, err := cfg.DB.GetAdvertisements(c.Request.Context(), params)
 	if err != nil {
 		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
-		return
+		return dto.GetAdsListResponse{}, false
+	}
+	hasNext := len(dbAds) > query.PageSize
+	if hasNext {
+		dbAds = dbAds[:query.PageSize]
+	}
+
+	// total uses exactly the same filters as the feed itself
+	var total *int64
+	if query.WithTotal {
+		count, err := cfg.DB.CountAdvertisements(
+			c.Request.Context(),
+			database.CountAdvertisementsParams{
+				MinPrice:      params.MinPrice,
+				MaxPrice:      params.MaxPrice,
+				Status:        params.Status,
+				UserID:        params.UserID,
+				IncludeBroken: params.IncludeBroken,
+				Query:         params.Query,
+				CategoryID:    params.CategoryID,
+			},
+		)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return dto.GetAdsListResponse{}, false
+		}
+		total = &count
+	}
+
+	var nextCursor string
+	if hasNext {
+		nextCursor, err = encodeCursor(cursorFromRow(dbAds[len(dbAds)-1], query.SortBy, query.Order, adsFilters(params)), cfg.Secret)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return dto.GetAdsListResponse{}, false
+		}
 	}
 
 	// aggregate ads from db to custom responseAds struct
 	responseAds := make([]dto.GetAdsResponse, len(dbAds))
 	for index, ad := range dbAds {
 		var isOwner *bool
-		if userID != uuid.Nil {
-			isOwnerVal := ad.UserID == userID
+		if viewerID != uuid.Nil {
+			isOwnerVal := ad.UserID == viewerID
 			isOwner = &isOwnerVal
 		}
 
 		responseAd := dto.GetAdsResponse{
+			ID:           ad.ID.String(),
 			Title:        ad.Title,
 			Description:  ad.Description,
 			ImageAddress: ad.ImageAddress,
 			AuthorLogin:  ad.AuthorLogin,
 			Price:        int(ad.Price),
+			CategoryID:   int(ad.CategoryID),
+			Status:       ad.Status,
+			CreatedAt:    ad.CreatedAt,
 			IsOwner:      isOwner,
 		}
+		// resized copies exist only for uploaded images
+		if ad.ImageID.Valid {
+			responseAd.ImageVariants = imageVariantsResponse(ad.ImageID.UUID, ad.ImageVariantWidths)
+		}
 		responseAds[index] = responseAd
 	}
-	c.JSON(http.StatusOK, responseAds)
+
+	response := dto.GetAdsListResponse{
+		Items:      responseAds,
+		PageSize:   query.PageSize,
+		Total:      total,
+		HasNext:    hasNext,
+		NextCursor: nextCursor,
+	}
+	if !cursorMode {
+		response.Page = query.Page
+	}
+
+	// the header is still set for clients written against the bare array response
+	if nextCursor != "" {
+		c.Header("X-Next-Cursor", nextCursor)
+	}
+	c.Header("Link", buildLinkHeader(c.Request.URL, pageLinks{
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		HasNext:    hasNext,
+		Total:      total,
+		CursorMode: cursorMode,
+		NextCursor: nextCursor,
+	}))
+	return response, true
 }
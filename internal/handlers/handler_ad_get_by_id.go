@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HandlerGetAdByID godoc
+//
+//	@Summary		Получить объявление
+//	@Description	Возвращает объявление целиком по его ID вместе с галереей изображений. Неопубликованные объявления доступны только их автору. Авторизованным пользователям доступно получение параметра `is_owner`.
+//	@Produce		json
+//	@Param			Authorization	header		string				false	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			id				path		string				true	"ID объявления"	format(uuid)
+//	@Success		200				{object}	dto.GetAdResponse	"Успешный ответ"
+//	@Failure		401				{object}	dto.ErrorResponse	"Невалидный или просроченный токен-доступа"
+//	@Failure		404				{object}	dto.ErrorResponse	"Объявление не найдено"
+//	@Failure		500				{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/ads/{id} [get]
+func (cfg *ApiConfig) HandlerGetAdByID(c *gin.Context) {
+	// same as in HandlerGetAds: auth header is optional and only affects `is_owner`
+	userID, _ := auth.UserID(c)
+
+	// malformed id can't belong to any ad, so it's just not found
+	adID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+		return
+	}
+
+	ad, err := cfg.DB.GetAdvertisementByID(c.Request.Context(), adID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// only published ads are public, the rest are visible to their owner only
+	if ad.Status != AdStatusPublished && ad.UserID != userID {
+		dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+		return
+	}
+
+	gallery, err := cfg.DB.GetAdImages(c.Request.Context(), ad.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	var isOwner *bool
+	if userID != uuid.Nil {
+		isOwnerVal := ad.UserID == userID
+		isOwner = &isOwnerVal
+	}
+
+	c.JSON(
+		http.StatusOK,
+		dto.GetAdResponse{
+			ID:           ad.ID.String(),
+			Title:        ad.Title,
+			Description:  ad.Description,
+			ImageAddress: ad.ImageAddress,
+			AuthorLogin:  ad.AuthorLogin,
+			Price:        int(ad.Price),
+			CategoryID:   int(ad.CategoryID),
+			Status:       ad.Status,
+			CreatedAt:    ad.CreatedAt,
+			UpdatedAt:    ad.UpdatedAt,
+			IsOwner:      isOwner,
+			ImageBroken:  ad.ImageBroken,
+			Images:       adImagesResponse(gallery),
+		},
+	)
+}
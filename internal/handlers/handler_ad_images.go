@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerUpdateAdImages godoc
+//
+//	@Summary		Изменить галерею объявления
+//	@Description	Заменяет галерею объявления целиком: порядок изображений в запросе становится их порядком в объявлении, обложка выбирается по индексу `cover_index`. Чтобы переставить изображения, достаточно передать уже существующие в новом порядке. Доступно только автору объявления, не более 10 изображений.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			id				path		string						true	"ID объявления"	format(uuid)
+//	@Param			body			body		dto.UpdateAdImagesRequest	true	"Изображения объявления"
+//	@Success		200				{object}	dto.GetAdResponse			"Галерея обновлена"
+//	@Failure		400				{object}	dto.ErrorResponse			"Неверный формат запроса"
+//	@Failure		401				{object}	dto.ErrorResponse			"Невалидный или просроченный токен-доступа"
+//	@Failure		403				{object}	dto.ErrorResponse			"Объявление принадлежит другому пользователю"
+//	@Failure		404				{object}	dto.ErrorResponse			"Объявление не найдено"
+//	@Failure		500				{object}	dto.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/api/ads/{id}/images [put]
+func (cfg *ApiConfig) HandlerUpdateAdImages(c *gin.Context) {
+	userID := auth.MustUserID(c)
+
+	ad, ok := cfg.getOwnedAd(c, userID)
+	if !ok {
+		return
+	}
+
+	input := dto.UpdateAdImagesRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+	if input.CoverIndex < 0 || (len(input.Images) > 0 && input.CoverIndex >= len(input.Images)) {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidCover.Error(), nil)
+		return
+	}
+
+	// images already in the gallery were validated before, so reordering doesn't hit remote hosts
+	currentGallery, err := cfg.DB.GetAdImages(c.Request.Context(), ad.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	knownAddresses := make(map[string]bool, len(currentGallery))
+	for _, image := range currentGallery {
+		knownAddresses[image.ImageAddress] = true
+	}
+
+	images, ok := cfg.resolveAdImages(c, userID, input.Images, knownAddresses)
+	if !ok {
+		return
+	}
+	cover := images[input.CoverIndex]
+
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	gallery, err := saveAdImages(c.Request.Context(), qtx, ad.ID, images, input.CoverIndex)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	updatedAt := time.Now().UTC()
+	err = qtx.UpdateAdvertisementCover(
+		c.Request.Context(),
+		database.UpdateAdvertisementCoverParams{
+			ID:           ad.ID,
+			ImageAddress: cover.Address,
+			ImageID:      cover.ImageID,
+			UpdatedAt:    updatedAt,
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	imageBroken := ad.ImageBroken
+	if cover.Address != ad.ImageAddress {
+		if err := qtx.ResetImageCheck(c.Request.Context(), ad.ID); err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		imageBroken = false
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	isOwner := true
+	c.JSON(
+		http.StatusOK,
+		dto.GetAdResponse{
+			ID:           ad.ID.String(),
+			Title:        ad.Title,
+			Description:  ad.Description,
+			ImageAddress: cover.Address,
+			AuthorLogin:  ad.AuthorLogin,
+			Price:        int(ad.Price),
+			CategoryID:   int(ad.CategoryID),
+			Status:       ad.Status,
+			CreatedAt:    ad.CreatedAt,
+			UpdatedAt:    updatedAt,
+			IsOwner:      &isOwner,
+			ImageBroken:  imageBroken,
+			Images:       adImagesResponse(gallery),
+		},
+	)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerUpdateAdStatus godoc
+//
+//	@Summary		Изменить статус объявления
+//	@Description	Переводит объявление в новый статус. Допустимые переходы: draft → published/archived, published → draft/reserved/sold/archived, reserved → published/sold/archived, sold → archived, archived → draft/published.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			id				path		string						true	"ID объявления"	format(uuid)
+//	@Param			body			body		dto.UpdateAdStatusRequest	true	"Новый статус"
+//	@Success		200				{object}	dto.GetAdResponse			"Статус изменён"
+//	@Failure		400				{object}	dto.ErrorResponse			"Неверный формат запроса"
+//	@Failure		401				{object}	dto.ErrorResponse			"Невалидный или просроченный токен-доступа"
+//	@Failure		403				{object}	dto.ErrorResponse			"Объявление принадлежит другому пользователю"
+//	@Failure		404				{object}	dto.ErrorResponse			"Объявление не найдено"
+//	@Failure		409				{object}	dto.ErrorResponse			"Недопустимый переход статуса или статус изменён параллельным запросом"
+//	@Failure		500				{object}	dto.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/api/ads/{id}/status [post]
+func (cfg *ApiConfig) HandlerUpdateAdStatus(c *gin.Context) {
+	userID := auth.MustUserID(c)
+
+	ad, ok := cfg.getOwnedAd(c, userID)
+	if !ok {
+		return
+	}
+
+	input := dto.UpdateAdStatusRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	if err := validateAdStatusTransition(ad.Status, input.Status); err != nil {
+		if errors.Is(err, ErrForbiddenStatusTransition) {
+			dto.ResponseWithError(c, http.StatusConflict, err.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	updatedAd, err := cfg.DB.UpdateAdvertisementStatus(
+		c.Request.Context(),
+		database.UpdateAdvertisementStatusParams{
+			ID:             ad.ID,
+			Status:         input.Status,
+			UpdatedAt:      time.Now().UTC(),
+			ExpectedStatus: ad.Status,
+		},
+	)
+	if err != nil {
+		// the transition was validated against a status another request has changed since
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusConflict, ErrAdStatusChanged.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	gallery, err := cfg.DB.GetAdImages(c.Request.Context(), ad.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	isOwner := true
+	c.JSON(
+		http.StatusOK,
+		dto.GetAdResponse{
+			ID:           updatedAd.ID.String(),
+			Title:        updatedAd.Title,
+			Description:  updatedAd.Description,
+			ImageAddress: updatedAd.ImageAddress,
+			AuthorLogin:  ad.AuthorLogin,
+			Price:        int(updatedAd.Price),
+			CategoryID:   int(updatedAd.CategoryID),
+			Status:       updatedAd.Status,
+			CreatedAt:    updatedAd.CreatedAt,
+			UpdatedAt:    updatedAd.UpdatedAt,
+			IsOwner:      &isOwner,
+			ImageBroken:  updatedAd.ImageBroken,
+			Images:       adImagesResponse(gallery),
+		},
+	)
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrNothingToUpdate = errors.New("no fields to update")
+
+// HandlerUpdateAd godoc
+//
+//	@Summary		Изменить объявление
+//	@Description	Частично обновляет объявление. Доступно только автору объявления, переданные поля проходят ту же валидацию, что и при создании. Новое изображение заменяет обложку объявления, остальная галерея изменяется через `PUT /api/ads/{id}/images`.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string					true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			id				path		string					true	"ID объявления"	format(uuid)
+//	@Param			body			body		dto.UpdateAdsRequest	true	"Изменяемые параметры объявления"
+//	@Success		200				{object}	dto.GetAdResponse		"Объявление обновлено"
+//	@Failure		400				{object}	dto.ErrorResponse		"Неверный формат запроса"
+//	@Failure		401				{object}	dto.ErrorResponse		"Невалидный или просроченный токен-доступа"
+//	@Failure		403				{object}	dto.ErrorResponse		"Объявление принадлежит другому пользователю"
+//	@Failure		404				{object}	dto.ErrorResponse		"Объявление не найдено"
+//	@Failure		409				{object}	dto.ErrorResponse		"Объявление изменено параллельным запросом"
+//	@Failure		500				{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/api/ads/{id} [patch]
+func (cfg *ApiConfig) HandlerUpdateAd(c *gin.Context) {
+	userID := auth.MustUserID(c)
+
+	ad, ok := cfg.getOwnedAd(c, userID)
+	if !ok {
+		return
+	}
+
+	inputAdParams := dto.UpdateAdsRequest{}
+	if err := c.BindJSON(&inputAdParams); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	// validate only the fields that are changing, the rest was validated on creation
+	params := database.UpdateAdvertisementParams{
+		ID:           ad.ID,
+		Title:        ad.Title,
+		Description:  ad.Description,
+		ImageAddress: ad.ImageAddress,
+		Price:        ad.Price,
+		CategoryID:   ad.CategoryID,
+		UpdatedAt:    time.Now().UTC(),
+		ImageID:      ad.ImageID,
+		// fields that aren't in the request are written back as they were read,
+		// so the update only applies if nobody has changed the ad since
+		ExpectedUpdatedAt: ad.UpdatedAt,
+	}
+	if inputAdParams.ImageID != nil {
+		if inputAdParams.ImageAddress != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, ErrAmbiguousAdImage.Error(), nil)
+			return
+		}
+		image, err := cfg.getOwnedImage(c.Request.Context(), userID, *inputAdParams.ImageID)
+		if err != nil {
+			if errors.Is(err, ErrImageNotFound) {
+				dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+				return
+			}
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		params.ImageID = uuid.NullUUID{UUID: image.ID, Valid: true}
+		params.ImageAddress = imageURL(image.ID)
+	}
+	// remote image is checked here, applyAdUpdate itself doesn't make any requests
+	if inputAdParams.ImageAddress != nil && *inputAdParams.ImageAddress != ad.ImageAddress {
+		if err := cfg.validateImage(c.Request.Context(), *inputAdParams.ImageAddress); err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	if err := applyAdUpdate(&params, inputAdParams); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// a new image replaces the cover of the gallery
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	updatedAd, err := qtx.UpdateAdvertisement(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusConflict, ErrAdChanged.Error(), nil)
+			return
+		}
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23503" {
+				dto.ResponseWithError(c, http.StatusBadRequest, "category not found", nil)
+				return
+			}
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if updatedAd.ImageAddress != ad.ImageAddress {
+		err = qtx.UpdateAdCoverImage(
+			c.Request.Context(),
+			database.UpdateAdCoverImageParams{
+				AdID:         updatedAd.ID,
+				ImageAddress: updatedAd.ImageAddress,
+				ImageID:      updatedAd.ImageID,
+			},
+		)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		// failures of the old image say nothing about the new one
+		if err := qtx.ResetImageCheck(c.Request.Context(), updatedAd.ID); err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		updatedAd.ImageBroken = false
+	}
+	gallery, err := qtx.GetAdImages(c.Request.Context(), updatedAd.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	isOwner := true
+	c.JSON(
+		http.StatusOK,
+		dto.GetAdResponse{
+			ID:           updatedAd.ID.String(),
+			Title:        updatedAd.Title,
+			Description:  updatedAd.Description,
+			ImageAddress: updatedAd.ImageAddress,
+			AuthorLogin:  ad.AuthorLogin,
+			Price:        int(updatedAd.Price),
+			CategoryID:   int(updatedAd.CategoryID),
+			Status:       updatedAd.Status,
+			CreatedAt:    updatedAd.CreatedAt,
+			UpdatedAt:    updatedAd.UpdatedAt,
+			IsOwner:      &isOwner,
+			ImageBroken:  updatedAd.ImageBroken,
+			Images:       adImagesResponse(gallery),
+		},
+	)
+}
+
+func applyAdUpdate(params *database.UpdateAdvertisementParams, input dto.UpdateAdsRequest) error {
+	if input.Title == nil && input.Description == nil && input.ImageAddress == nil && input.ImageID == nil &&
+		input.Price == nil && input.CategoryID == nil {
+		return ErrNothingToUpdate
+	}
+
+	if input.Title != nil {
+		if err := validateTitle(*input.Title); err != nil {
+			return err
+		}
+		params.Title = *input.Title
+	}
+	if input.Description != nil {
+		if err := validateDescription(*input.Description); err != nil {
+			return err
+		}
+		params.Description = *input.Description
+	}
+	if input.Price != nil {
+		if err := validatePrice(*input.Price); err != nil {
+			return err
+		}
+		params.Price = int32(*input.Price)
+	}
+	if input.CategoryID != nil {
+		params.CategoryID = int32(*input.CategoryID)
+	}
+	if input.ImageAddress != nil && *input.ImageAddress != params.ImageAddress {
+		params.ImageAddress = *input.ImageAddress
+		params.ImageID = uuid.NullUUID{}
+	}
+	return nil
+}
+
+// getOwnedAd loads the ad from the `id` path param and makes sure it belongs to userID.
+// On failure the error response is already written and ok is false.
+func (cfg *ApiConfig) getOwnedAd(c *gin.Context, userID uuid.UUID) (database.GetAdvertisementByIDRow, bool) {
+	adID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+		return database.GetAdvertisementByIDRow{}, false
+	}
+
+	ad, err := cfg.DB.GetAdvertisementByID(c.Request.Context(), adID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+			return database.GetAdvertisementByIDRow{}, false
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return database.GetAdvertisementByIDRow{}, false
+	}
+
+	if ad.UserID != userID {
+		dto.ResponseWithError(c, http.StatusForbidden, "you are not the owner of this advertisement", nil)
+		return database.GetAdvertisementByIDRow{}, false
+	}
+	return ad, true
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+)
+
+var ErrOwnRoleChange = errors.New("you can't change your own role")
+
+// HandlerUpdateUserRole godoc
+//
+//	@Summary		Изменить роль пользователя
+//	@Description	Назначает пользователю роль `user`, `moderator` или `admin`. Доступно только администраторам. Выданные пользователю JWT отзываются, новая роль попадает в токен при следующем обновлении через `/api/auth/refresh`.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			login			path		string						true	"Логин пользователя"
+//	@Param			body			body		dto.UpdateUserRoleRequest	true	"Новая роль"
+//	@Success		200				{object}	dto.UserRoleResponse		"Роль изменена"
+//	@Failure		400				{object}	dto.ErrorResponse			"Неверный формат запроса"
+//	@Failure		401				{object}	dto.ErrorResponse			"Невалидный или просроченный токен-доступа"
+//	@Failure		403				{object}	dto.ErrorResponse			"Недостаточно прав"
+//	@Failure		404				{object}	dto.ErrorResponse			"Пользователь не найден"
+//	@Failure		409				{object}	dto.ErrorResponse			"Нельзя изменить собственную роль"
+//	@Failure		500				{object}	dto.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/api/admin/users/{login}/role [put]
+func (cfg *ApiConfig) HandlerUpdateUserRole(c *gin.Context) {
+	adminID := auth.MustUserID(c)
+
+	input := dto.UpdateUserRoleRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByLogin(c.Request.Context(), c.Param("login"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, "user not found", nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	// otherwise the last admin could demote themselves and nobody could manage roles anymore
+	if user.ID == adminID {
+		dto.ResponseWithError(c, http.StatusConflict, ErrOwnRoleChange.Error(), nil)
+		return
+	}
+
+	updatedUser, err := cfg.DB.UpdateUserRole(
+		c.Request.Context(),
+		database.UpdateUserRoleParams{
+			Login:     user.Login,
+			Role:      input.Role,
+			UpdatedAt: time.Now().UTC(),
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	// access tokens carry the role, the old ones mustn't outlive the change
+	if _, err := cfg.Revocations.RevokeAll(c.Request.Context(), updatedUser.ID); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		dto.UserRoleResponse{
+			ID:        updatedUser.ID,
+			Login:     updatedUser.Login,
+			Role:      updatedUser.Role,
+			UpdatedAt: updatedUser.UpdatedAt,
+		},
+	)
+}
@@ -1,24 +1,34 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/englandrecoil/go-marketplace-service/internal/auth"
 	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
 	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/loginguard"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // HandlerAuth godoc
 //
 //	@Summary		Аутентифицировать пользователя
-//	@Description	Аутентифицирует пользователя по заданному логину и паролю и возвращает JWT
+//	@Description	Аутентифицирует пользователя по заданному логину и паролю и возвращает JWT вместе с refresh-токеном. Если у пользователя включена двухфакторная аутентификация, вместо них возвращается `mfa_token`, который вместе с кодом нужно передать в `POST /api/auth/mfa`.
 //	@Accept			json
 //	@Produce		json
 //	@Param			credentials	body		dto.CredentialsRequest	true	"Данные пользователя для входа"
 //	@Success		200			{object}	dto.AuthResponse		"Успешная аутентификация"
+//	@Success		202			{object}	dto.MFARequiredResponse	"Пароль верный, требуется второй фактор"
 //	@Failure		400			{object}	dto.ErrorResponse		"Неверный формат запроса"
 //	@Failure		401			{object}	dto.ErrorResponse		"Неверный логин или пароль"
+//	@Failure		429			{object}	dto.ErrorResponse		"Слишком много неудачных попыток входа, время ожидания в заголовке Retry-After"
 //	@Failure		500			{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
 //	@Router			/api/auth [post]
 func (cfg *ApiConfig) HandlerAuth(c *gin.Context) {
@@ -28,28 +38,95 @@ func (cfg *ApiConfig) HandlerAuth(c *gin.Context) {
 		return
 	}
 
-	// compare given password and hash from db
+	// the attempt counts as failed until the password turns out right
+	attempt, retryAfter, err := cfg.LoginGuard.Reserve(c.Request.Context(), inputCredentials.Login, c.ClientIP())
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if retryAfter > 0 {
+		respondTooManyAttempts(c, retryAfter)
+		return
+	}
+
+	// compare given password and hash from db, unknown logins cost as much as wrong passwords
 	dbUser, err := cfg.DB.GetUserByLogin(c.Request.Context(), inputCredentials.Login)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err != nil {
+		auth.CheckPasswordDummy(inputCredentials.Password)
+	} else {
+		err = auth.CheckPasswordHash(inputCredentials.Password, dbUser.HashedPassword)
+	}
 	if err != nil {
 		dto.ResponseWithError(c, http.StatusUnauthorized, "invalid login or password", err)
 		return
 	}
-	if err = auth.CheckPasswordHash(inputCredentials.Password, dbUser.HashedPassword); err != nil {
-		dto.ResponseWithError(c, http.StatusUnauthorized, "invalid login or password", err)
+	if err := cfg.LoginGuard.Release(c.Request.Context(), attempt); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// with 2FA the password only gets a token for the second step, the failure counter
+	// stays until that step passes, otherwise each login would allow more code guesses
+	twoFactor, err := cfg.twoFactorEnabled(c.Request.Context(), dbUser.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if twoFactor {
+		mfaToken, err := auth.MakeMFAToken(dbUser.ID, cfg.Keys, constants.MFATokenLifetime)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		c.JSON(http.StatusAccepted, dto.MFARequiredResponse{MFAToken: mfaToken})
 		return
 	}
 
-	// make JWT and send it to user
-	token, err := auth.MakeJWT(dbUser.ID, cfg.Secret, constants.TokenExpirationTime)
+	if err := cfg.LoginGuard.Reset(c.Request.Context(), inputCredentials.Login); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// every login starts a new family of refresh tokens
+	tokens, err := cfg.issueTokens(c.Request.Context(), cfg.DB, dbUser, uuid.New())
 	if err != nil {
 		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
 		return
 	}
 
-	c.JSON(
-		http.StatusOK,
-		dto.AuthResponse{
-			Token: token,
-		},
-	)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// respondTooManyAttempts rejects a login attempt made while the login or the IP is locked.
+func respondTooManyAttempts(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	dto.ResponseWithError(c, http.StatusTooManyRequests, loginguard.ErrTooManyAttempts.Error(), nil)
+}
+
+// checkPassword verifies the password of a logged in user before sensitive changes. Wrong
+// passwords count as failed logins, so a stolen access token doesn't make guessing any easier.
+// On a wrong password it responds with 403 and wrongPasswordErr, it returns whether to go on.
+func (cfg *ApiConfig) checkPassword(c *gin.Context, user database.User, password string, wrongPasswordErr error) bool {
+	attempt, retryAfter, err := cfg.LoginGuard.Reserve(c.Request.Context(), user.Login, c.ClientIP())
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return false
+	}
+	if retryAfter > 0 {
+		respondTooManyAttempts(c, retryAfter)
+		return false
+	}
+	if err := auth.CheckPasswordHash(password, user.HashedPassword); err != nil {
+		dto.ResponseWithError(c, http.StatusForbidden, wrongPasswordErr.Error(), err)
+		return false
+	}
+	if err := cfg.LoginGuard.Release(c.Request.Context(), attempt); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return false
+	}
+	return true
 }
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HandlerLogout godoc
+//
+//	@Summary		Выйти из аккаунта
+//	@Description	Отзывает текущий JWT и, если передан, refresh-токен вместе со всей цепочкой выданных по нему токенов. С `all: true` отзываются все JWT и refresh-токены пользователя, выданные до этого момента, на всех устройствах. Тело запроса необязательно.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header	string				true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			logout			body	dto.LogoutRequest	false	"Параметры выхода"
+//	@Success		204				"Выход выполнен"
+//	@Failure		400				{object}	dto.ErrorResponse	"Неверный формат запроса"
+//	@Failure		401				{object}	dto.ErrorResponse	"Отсутствует или недействителен токен доступа"
+//	@Failure		500				{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/auth/logout [post]
+func (cfg *ApiConfig) HandlerLogout(c *gin.Context) {
+	// the route uses RequireAuth
+	accessToken, _ := auth.Token(c)
+
+	input := dto.LogoutRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&input); err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+			return
+		}
+	}
+
+	if input.All {
+		tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		defer tx.Rollback()
+		validAfter, err := logoutEverywhere(c.Request.Context(), cfg.DB.WithTx(tx), accessToken.UserID)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		cfg.Revocations.SetValidAfter(accessToken.UserID, validAfter)
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if err := cfg.Revocations.Revoke(c.Request.Context(), accessToken); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if input.RefreshToken != "" {
+		refreshToken, err := cfg.DB.GetRefreshTokenByHash(c.Request.Context(), auth.HashRefreshToken(input.RefreshToken))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		// someone else's token can't be revoked, an unknown one is already useless
+		if err == nil && refreshToken.UserID == accessToken.UserID {
+			err = cfg.DB.RevokeRefreshTokenFamily(
+				c.Request.Context(),
+				database.RevokeRefreshTokenFamilyParams{
+					FamilyID:  refreshToken.FamilyID,
+					RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+				},
+			)
+			if err != nil {
+				dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+				return
+			}
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// logoutEverywhere invalidates every access and refresh token issued to the user so far
+// and returns the moment the access tokens are rejected up to. db is usually a transaction,
+// once it's committed the caller passes the result to Revocations.SetValidAfter.
+func logoutEverywhere(ctx context.Context, db *database.Queries, userID uuid.UUID) (time.Time, error) {
+	// the column keeps microseconds
+	validAfter := time.Now().UTC().Truncate(time.Microsecond)
+	err := db.UpdateUserTokensValidAfter(
+		ctx,
+		database.UpdateUserTokensValidAfterParams{
+			ID:               userID,
+			TokensValidAfter: sql.NullTime{Time: validAfter, Valid: true},
+		},
+	)
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = db.RevokeUserRefreshTokens(
+		ctx,
+		database.RevokeUserRefreshTokensParams{
+			UserID:    userID,
+			RevokedAt: sql.NullTime{Time: validAfter, Valid: true},
+		},
+	)
+	return validAfter, err
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// HandlerRefreshToken godoc
+//
+//	@Summary		Обновить токены
+//	@Description	Обменивает refresh-токен на новую пару из JWT и refresh-токена, старый refresh-токен после этого недействителен. Повторное предъявление уже использованного refresh-токена считается признаком кражи: все токены, выданные по цепочке от того же входа, отзываются.
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	body		dto.RefreshTokenRequest	true	"Refresh-токен"
+//	@Success		200		{object}	dto.AuthResponse		"Новая пара токенов"
+//	@Failure		400		{object}	dto.ErrorResponse		"Неверный формат запроса"
+//	@Failure		401		{object}	dto.ErrorResponse		"Refresh-токен недействителен, истёк или уже был использован"
+//	@Failure		500		{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/api/auth/refresh [post]
+func (cfg *ApiConfig) HandlerRefreshToken(c *gin.Context) {
+	input := dto.RefreshTokenRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	refreshToken, err := cfg.DB.GetRefreshTokenByHash(c.Request.Context(), auth.HashRefreshToken(input.RefreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusUnauthorized, ErrInvalidRefreshToken.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if refreshToken.RevokedAt.Valid {
+		dto.ResponseWithError(c, http.StatusUnauthorized, ErrInvalidRefreshToken.Error(), nil)
+		return
+	}
+	if refreshToken.UsedAt.Valid {
+		cfg.revokeTokenFamily(c, refreshToken.FamilyID)
+		return
+	}
+	now := time.Now().UTC()
+	if !refreshToken.ExpiresAt.After(now) {
+		dto.ResponseWithError(c, http.StatusUnauthorized, ErrRefreshTokenExpired.Error(), nil)
+		return
+	}
+
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	markedRows, err := qtx.MarkRefreshTokenUsed(
+		c.Request.Context(),
+		database.MarkRefreshTokenUsedParams{
+			ID:     refreshToken.ID,
+			UsedAt: sql.NullTime{Time: now, Valid: true},
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if markedRows == 0 {
+		// the token was used or revoked by a concurrent request
+		tx.Rollback()
+		cfg.revokeTokenFamily(c, refreshToken.FamilyID)
+		return
+	}
+
+	// the role may have changed since the previous token
+	user, err := qtx.GetUserByID(c.Request.Context(), refreshToken.UserID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	tokens, err := cfg.issueTokens(c.Request.Context(), qtx, user, refreshToken.FamilyID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// revokeTokenFamily handles reuse of a refresh token: either the user or an attacker holds
+// a stolen copy, and there's no telling which one, so nobody keeps the session.
+func (cfg *ApiConfig) revokeTokenFamily(c *gin.Context, familyID uuid.UUID) {
+	err := cfg.DB.RevokeRefreshTokenFamily(
+		c.Request.Context(),
+		database.RevokeRefreshTokenFamilyParams{
+			FamilyID:  familyID,
+			RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	dto.ResponseWithError(c, http.StatusUnauthorized, ErrRefreshTokenReused.Error(), nil)
+}
+
+// issueTokens makes an access token and a refresh token belonging to familyID.
+// The access token is dated after user.TokensValidAfter, so it survives a logout made in the same second.
+func (cfg *ApiConfig) issueTokens(ctx context.Context, db *database.Queries, user database.User, familyID uuid.UUID) (dto.AuthResponse, error) {
+	accessToken, err := auth.MakeJWTAfter(user.ID, user.Role, cfg.Keys, constants.TokenExpirationTime, user.TokensValidAfter.Time)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	now := time.Now().UTC()
+	_, err = db.CreateRefreshToken(
+		ctx,
+		database.CreateRefreshTokenParams{
+			UserID:    user.ID,
+			FamilyID:  familyID,
+			TokenHash: auth.HashRefreshToken(refreshToken),
+			CreatedAt: now,
+			ExpiresAt: now.Add(constants.RefreshTokenLifetime),
+		},
+	)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	return dto.AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerGetCategories godoc
+//
+//	@Summary		Получить категории
+//	@Description	Возвращает дерево категорий объявлений
+//	@Produce		json
+//	@Success		200	{array}		dto.CategoryResponse	"Успешный ответ"
+//	@Failure		500	{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/api/categories [get]
+func (cfg *ApiConfig) HandlerGetCategories(c *gin.Context) {
+	dbCategories, err := cfg.DB.GetCategories(c.Request.Context())
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, buildCategoryTree(dbCategories))
+}
+
+func buildCategoryTree(categories []database.Category) []dto.CategoryResponse {
+	childrenByParent := make(map[int32][]database.Category)
+	roots := []database.Category{}
+	for _, category := range categories {
+		if !category.ParentID.Valid {
+			roots = append(roots, category)
+			continue
+		}
+		childrenByParent[category.ParentID.Int32] = append(childrenByParent[category.ParentID.Int32], category)
+	}
+
+	var build func(nodes []database.Category) []dto.CategoryResponse
+	build = func(nodes []database.Category) []dto.CategoryResponse {
+		tree := make([]dto.CategoryResponse, len(nodes))
+		for index, node := range nodes {
+			tree[index] = dto.CategoryResponse{
+				ID:       int(node.ID),
+				Name:     node.Name,
+				Slug:     node.Slug,
+				Children: build(childrenByParent[node.ID]),
+			}
+		}
+		return tree
+	}
+	return build(roots)
+}
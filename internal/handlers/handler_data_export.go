@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrDataExportNotFound = errors.New("data export not found")
+
+// HandlerCreateDataExport godoc
+//
+//	@Summary		Запросить выгрузку данных
+//	@Description	Запускает подготовку ZIP-архива со всеми данными, которые сервис хранит о текущем пользователе: профиль, объявления, загруженные изображения, сессии и события безопасности. Архив собирается в фоне, его статус можно узнать по ссылке из заголовка Location. Одновременно готовится только одна выгрузка, повторный запрос возвращает уже начатую.
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string					true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Success		202				{object}	dto.DataExportResponse	"Выгрузка поставлена в очередь"
+//	@Header			202				{string}	Location				"Адрес для проверки статуса выгрузки"
+//	@Failure		401				{object}	dto.ErrorResponse		"Отсутствует или недействителен токен доступа"
+//	@Failure		500				{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/api/users/me/exports [post]
+func (cfg *ApiConfig) HandlerCreateDataExport(c *gin.Context) {
+	userID := auth.MustUserID(c)
+
+	export, err := cfg.DB.CreateDataExport(
+		c.Request.Context(),
+		database.CreateDataExportParams{
+			UserID:    userID,
+			CreatedAt: time.Now().UTC(),
+		},
+	)
+	if err != nil {
+		// the user already has an export in progress, it's returned instead
+		pgErr, ok := err.(*pq.Error)
+		if !ok || pgErr.Code != "23505" {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		export, err = cfg.DB.GetUnfinishedUserDataExport(c.Request.Context(), userID)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+	}
+
+	c.Header("Location", "/api/users/me/exports/"+export.ID.String())
+	c.JSON(http.StatusAccepted, cfg.dataExportResponse(export, time.Now().UTC()))
+}
+
+// HandlerGetDataExport godoc
+//
+//	@Summary		Получить статус выгрузки данных
+//	@Description	Возвращает статус выгрузки. Когда архив готов, в `download_url` возвращается ссылка на скачивание, которая не требует токена и действует 15 минут; за новой ссылкой можно обратиться повторно. Архив хранится 24 часа после подготовки.
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string					true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			id				path		string					true	"ID выгрузки"	format(uuid)
+//	@Success		200				{object}	dto.DataExportResponse	"Статус выгрузки"
+//	@Failure		401				{object}	dto.ErrorResponse		"Отсутствует или недействителен токен доступа"
+//	@Failure		404				{object}	dto.ErrorResponse		"Выгрузка не найдена или уже удалена"
+//	@Failure		500				{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/api/users/me/exports/{id} [get]
+func (cfg *ApiConfig) HandlerGetDataExport(c *gin.Context) {
+	export, ok := cfg.getDataExport(c)
+	if !ok {
+		return
+	}
+	// someone else's export doesn't exist for this user
+	if export.UserID != auth.MustUserID(c) {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrDataExportNotFound.Error(), nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg.dataExportResponse(export, time.Now().UTC()))
+}
+
+// HandlerDownloadDataExport godoc
+//
+//	@Summary		Скачать выгрузку данных
+//	@Description	Отдаёт ZIP-архив выгрузки. Ссылка с параметрами `expires` и `signature` берётся из `download_url` ответа о статусе выгрузки, токен не нужен.
+//	@Produce		application/zip
+//	@Param			id			path		string				true	"ID выгрузки"	format(uuid)
+//	@Param			expires		query		int					true	"Время окончания действия ссылки (Unix time)"
+//	@Param			signature	query		string				true	"Подпись ссылки"
+//	@Success		200			{file}		file				"ZIP-архив"
+//	@Failure		403			{object}	dto.ErrorResponse	"Ссылка недействительна или истекла"
+//	@Failure		404			{object}	dto.ErrorResponse	"Выгрузка не найдена или уже удалена"
+//	@Failure		500			{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/exports/{id}/download [get]
+func (cfg *ApiConfig) HandlerDownloadDataExport(c *gin.Context) {
+	now := time.Now().UTC()
+	if err := verifyExportLink(c.Param("id"), c.Query("expires"), c.Query("signature"), cfg.Secret, now); err != nil {
+		dto.ResponseWithError(c, http.StatusForbidden, err.Error(), nil)
+		return
+	}
+
+	export, ok := cfg.getDataExport(c)
+	if !ok {
+		return
+	}
+	if export.Status != DataExportReady || !export.ExpiresAt.Time.After(now) {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrDataExportNotFound.Error(), nil)
+		return
+	}
+	// links signed before the account was deleted must not outlive it
+	owner, err := cfg.DB.GetUserByID(c.Request.Context(), export.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err != nil || owner.DeletedAt.Valid {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrDataExportNotFound.Error(), nil)
+		return
+	}
+
+	content, err := cfg.Storage.Get(c.Request.Context(), export.StorageKey.String)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			dto.ResponseWithError(c, http.StatusNotFound, ErrDataExportNotFound.Error(), err)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer content.Close()
+
+	// personal data must not stay in shared caches
+	c.DataFromReader(
+		http.StatusOK,
+		export.Size.Int64,
+		"application/zip",
+		content,
+		map[string]string{
+			"Cache-Control":          "private, no-store",
+			"Content-Disposition":    `attachment; filename="data-export-` + export.CreatedAt.Format("2006-01-02") + `.zip"`,
+			"X-Content-Type-Options": "nosniff",
+		},
+	)
+}
+
+// getDataExport loads the export from the `id` path parameter, on failure the error
+// response is already written and ok is false.
+func (cfg *ApiConfig) getDataExport(c *gin.Context) (database.DataExport, bool) {
+	// malformed id can't belong to any export, so it's just not found
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrDataExportNotFound.Error(), nil)
+		return database.DataExport{}, false
+	}
+
+	export, err := cfg.DB.GetDataExport(c.Request.Context(), exportID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, ErrDataExportNotFound.Error(), nil)
+			return database.DataExport{}, false
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return database.DataExport{}, false
+	}
+	return export, true
+}
+
+func (cfg *ApiConfig) dataExportResponse(export database.DataExport, now time.Time) dto.DataExportResponse {
+	response := dto.DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: nullTimePtr(export.CompletedAt),
+		ExpiresAt:   nullTimePtr(export.ExpiresAt),
+	}
+	if export.Status == DataExportReady && export.ExpiresAt.Time.After(now) {
+		// the link can't outlive the archive
+		linkExpiresAt := now.Add(constants.ExportLinkLifetime)
+		if export.ExpiresAt.Time.Before(linkExpiresAt) {
+			linkExpiresAt = export.ExpiresAt.Time
+		}
+		response.Size = export.Size.Int64
+		response.DownloadURL = exportDownloadURL(export.ID, linkExpiresAt, cfg.Secret)
+		response.DownloadURLExpiresAt = &linkExpiresAt
+	}
+	return response
+}
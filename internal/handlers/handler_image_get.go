@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/storage"
+	"github.com/englandrecoil/go-marketplace-service/internal/thumbnail"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HandlerGetImage godoc
+//
+//	@Summary		Получить изображение
+//	@Description	Отдаёт загруженное изображение. Изображения удалённых аккаунтов не отдаются.
+//	@Produce		image/jpeg,image/png,image/gif,image/webp
+//	@Param			id	path		string				true	"ID изображения"	format(uuid)
+//	@Success		200	{file}		binary				"Изображение"
+//	@Failure		404	{object}	dto.ErrorResponse	"Изображение не найдено или его владелец удалил аккаунт"
+//	@Failure		500	{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/images/{id} [get]
+func (cfg *ApiConfig) HandlerGetImage(c *gin.Context) {
+	imageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), nil)
+		return
+	}
+
+	// images of deleted accounts stay in the storage until the purge, but aren't served anymore
+	image, err := cfg.DB.GetPublicImageByID(c.Request.Context(), imageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	cfg.serveImage(c, image.StorageKey, image.ContentType, int64(image.Size))
+}
+
+// HandlerGetImageVariant godoc
+//
+//	@Summary		Получить уменьшенную копию изображения
+//	@Description	Отдаёт уменьшенную копию загруженного изображения заданной ширины. Доступные ширины перечислены в `variants` ответа на загрузку и в `image_variants` ленты объявлений.
+//	@Produce		image/jpeg,image/png
+//	@Param			id		path		string				true	"ID изображения"	format(uuid)
+//	@Param			width	path		int					true	"Ширина копии"		example(600)
+//	@Success		200		{file}		binary				"Изображение"
+//	@Failure		404		{object}	dto.ErrorResponse	"Изображение не найдено или его владелец удалил аккаунт"
+//	@Failure		500		{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/images/{id}/variants/{width} [get]
+func (cfg *ApiConfig) HandlerGetImageVariant(c *gin.Context) {
+	imageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), nil)
+		return
+	}
+	width, err := strconv.Atoi(c.Param("width"))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), nil)
+		return
+	}
+
+	image, err := cfg.DB.GetPublicImageByID(c.Request.Context(), imageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if !slices.Contains(image.VariantWidths, int32(width)) {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), nil)
+		return
+	}
+
+	// format of variants depends only on the format of the original
+	contentType := thumbnail.VariantContentType(image.ContentType)
+	cfg.serveImage(c, variantStorageKey(image.ID, width, imageExtensions[contentType]), contentType, -1)
+}
+
+// serveImage streams the stored image, size is -1 if it's unknown.
+func (cfg *ApiConfig) serveImage(c *gin.Context, storageKey, contentType string, size int64) {
+	content, err := cfg.Storage.Get(c.Request.Context(), storageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), err)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer content.Close()
+
+	// uploaded images never change, so clients may cache them forever
+	c.DataFromReader(
+		http.StatusOK,
+		size,
+		contentType,
+		content,
+		map[string]string{
+			"Cache-Control":          "public, max-age=31536000, immutable",
+			"X-Content-Type-Options": "nosniff",
+		},
+	)
+}
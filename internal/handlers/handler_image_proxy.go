@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var ErrImageUnavailable = errors.New("couldn't fetch image from its host")
+
+// HandlerGetProxiedImage godoc
+//
+//	@Summary		Получить изображение объявления через прокси
+//	@Description	Отдаёт изображение опубликованного объявления с нашего домена. Внешнее изображение скачивается один раз и дальше отдаётся из кэша на диске, неудачная загрузка повторяется не раньше чем через минуту, поддерживаются заголовки `ETag`/`If-None-Match` и `Last-Modified`/`If-Modified-Since`. Для загруженных изображений выполняется перенаправление на `/api/images/{id}`.
+//	@Produce		image/jpeg,image/png,image/gif,image/webp
+//	@Param			adID	path		string				true	"ID объявления"	format(uuid)
+//	@Success		200		{file}		binary				"Изображение"
+//	@Success		304		{string}	string				"Изображение не изменилось"
+//	@Failure		404		{object}	dto.ErrorResponse	"Объявление не найдено или его изображение недоступно"
+//	@Failure		500		{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Failure		502		{object}	dto.ErrorResponse	"Не удалось получить изображение с внешнего хоста"
+//	@Router			/api/images/proxy/{adID} [get]
+func (cfg *ApiConfig) HandlerGetProxiedImage(c *gin.Context) {
+	adID, err := uuid.Parse(c.Param("adID"))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+		return
+	}
+
+	ad, err := cfg.DB.GetAdvertisementByID(c.Request.Context(), adID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if ad.Status != AdStatusPublished {
+		dto.ResponseWithError(c, http.StatusNotFound, "advertisement not found", nil)
+		return
+	}
+	// dead images are hidden from the feed, there is nothing to fetch either
+	if ad.ImageBroken {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrImageNotFound.Error(), nil)
+		return
+	}
+
+	// uploaded images are already served by us
+	if ad.ImageID.Valid {
+		c.Redirect(http.StatusFound, imageURL(ad.ImageID.UUID))
+		return
+	}
+
+	// the key is the address itself, so a changed image is fetched again
+	file, entry, ok := cfg.ImageCache.Get(ad.ImageAddress)
+	if !ok {
+		// concurrent requests wait for the same download, it isn't cancelled when the first of them goes away
+		ctx := context.WithoutCancel(c.Request.Context())
+		err := cfg.ProxyFetches.Do(ad.ImageAddress, func() error {
+			data, contentType, err := cfg.fetchRemoteImage(ctx, ad.ImageAddress)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrImageUnavailable, err)
+			}
+			_, err = cfg.ImageCache.Put(ad.ImageAddress, contentType, bytes.NewReader(data))
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, ErrImageUnavailable) {
+				dto.ResponseWithError(c, http.StatusBadGateway, ErrImageUnavailable.Error(), err)
+				return
+			}
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		file, entry, ok = cfg.ImageCache.Get(ad.ImageAddress)
+		if !ok {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", nil)
+			return
+		}
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", entry.ContentType)
+	c.Header("ETag", entry.ETag)
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	// handles conditional and range requests
+	http.ServeContent(c.Writer, c.Request, "", entry.FetchedAt, file)
+}
+
+// fetchRemoteImage downloads the whole remote image. Its content is checked the same way as uploads,
+// so nothing but images is ever served from our domain.
+func (cfg *ApiConfig) fetchRemoteImage(ctx context.Context, imageUrl string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageUrl, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	res, err := cfg.Fetcher.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("image server responded with status %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, constants.MaxImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > constants.MaxImageSize {
+		return nil, "", ErrImageTooBig
+	}
+	info, err := cfg.ImagePolicy.Check(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, info.ContentType, nil
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecheck"
+	"github.com/englandrecoil/go-marketplace-service/internal/thumbnail"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrImageNotFound      = errors.New("image not found")
+	ErrImageTooBig        = errors.New("image size is too big")
+	ErrInvalidImageFormat = imagecheck.ErrInvalidFormat
+)
+
+// imageExtensions maps content types of stored images and their variants to file extensions.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// multipart headers and boundaries take some space on top of the file itself
+const multipartOverhead = 512 * 1024
+
+// HandlerUploadImage godoc
+//
+//	@Summary		Загрузить изображение
+//	@Description	Загружает изображение (по умолчанию jpeg/png, список форматов настраивается; не более 10 МБ и 6000×6000 пикселей) и возвращает его ID, который можно указать в объявлении. Формат определяется по содержимому файла, а не по заголовкам. Для изображения создаются уменьшенные копии шириной 200, 600 и 1200 пикселей (не шире оригинала) с учётом EXIF-ориентации и без метаданных: для jpeg и webp в формате jpeg, для png и gif в формате png. Из оригинала удаляются метаданные (EXIF, XMP, IPTC, комментарии), у jpeg сохраняется только ориентация.
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string					true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			image			formData	file					true	"Файл изображения"
+//	@Success		201				{object}	dto.UploadImageResponse	"Изображение загружено"
+//	@Failure		400				{object}	dto.ErrorResponse		"Неверный формат или размер изображения"
+//	@Failure		401				{object}	dto.ErrorResponse		"Невалидный или просроченный токен-доступа"
+//	@Failure		500				{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/api/images [post]
+func (cfg *ApiConfig) HandlerUploadImage(c *gin.Context) {
+	userID := auth.MustUserID(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxImageSize+multipartOverhead)
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			dto.ResponseWithError(c, http.StatusBadRequest, ErrImageTooBig.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusBadRequest, "image file is required", err)
+		return
+	}
+	if fileHeader.Size > constants.MaxImageSize {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrImageTooBig.Error(), nil)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, constants.MaxImageSize))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// don't trust the client's Content-Type, look at the bytes instead
+	info, err := cfg.ImagePolicy.Check(bytes.NewReader(data))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	contentType := info.ContentType
+	extension := imageExtensions[contentType]
+
+	// decoding the whole image also catches files that only look like images by their first bytes
+	variants, err := thumbnail.Generate(data, thumbnail.DefaultWidths)
+	if err != nil {
+		if errors.Is(err, thumbnail.ErrTooManyPixels) {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidImageFormat.Error(), err)
+		return
+	}
+	// the original is served as is, so location and camera details must not reach other users
+	data, err = thumbnail.StripMetadata(data, contentType)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidImageFormat.Error(), err)
+		return
+	}
+
+	imageID := uuid.New()
+	storageKey := "images/" + imageID.String() + extension
+	storedKeys := []string{storageKey}
+	cleanup := func() {
+		for _, key := range storedKeys {
+			cfg.Storage.Delete(c.Request.Context(), key)
+		}
+	}
+	if err := cfg.Storage.Put(c.Request.Context(), storageKey, bytes.NewReader(data)); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	variantWidths := make([]int32, 0, len(variants))
+	for _, variant := range variants {
+		key := variantStorageKey(imageID, variant.Width, imageExtensions[variant.ContentType])
+		storedKeys = append(storedKeys, key)
+		if err := cfg.Storage.Put(c.Request.Context(), key, bytes.NewReader(variant.Data)); err != nil {
+			cleanup()
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		variantWidths = append(variantWidths, int32(variant.Width))
+	}
+
+	image, err := cfg.DB.CreateImage(
+		c.Request.Context(),
+		database.CreateImageParams{
+			ID:            imageID,
+			UserID:        userID,
+			StorageKey:    storageKey,
+			ContentType:   contentType,
+			Size:          int32(len(data)),
+			CreatedAt:     time.Now().UTC(),
+			VariantWidths: variantWidths,
+		},
+	)
+	if err != nil {
+		cleanup()
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.JSON(
+		http.StatusCreated,
+		dto.UploadImageResponse{
+			ID:          image.ID.String(),
+			URL:         imageURL(image.ID),
+			ContentType: image.ContentType,
+			Size:        int(image.Size),
+			CreatedAt:   image.CreatedAt,
+			Variants:    imageVariantsResponse(image.ID, image.VariantWidths),
+		},
+	)
+}
+
+// imageURL is the address uploaded images are served from, it's what ads store in `image_address`.
+func imageURL(imageID uuid.UUID) string {
+	return "/api/images/" + imageID.String()
+}
+
+// variantStorageKey is where the resized copy of the image is stored, next to the original.
+func variantStorageKey(imageID uuid.UUID, width int, extension string) string {
+	return fmt.Sprintf("images/%s_%d%s", imageID, width, extension)
+}
+
+func imageVariantsResponse(imageID uuid.UUID, widths []int32) []dto.ImageVariantResponse {
+	variants := make([]dto.ImageVariantResponse, len(widths))
+	for index, width := range widths {
+		variants[index] = dto.ImageVariantResponse{
+			Width: int(width),
+			URL:   fmt.Sprintf("%s/variants/%d", imageURL(imageID), width),
+		}
+	}
+	return variants
+}
+
+// getOwnedImage loads an uploaded image by its ID making sure it was uploaded by userID.
+// Someone else's image is reported as missing so IDs can't be probed.
+func (cfg *ApiConfig) getOwnedImage(ctx context.Context, userID uuid.UUID, rawImageID string) (database.Image, error) {
+	imageID, err := uuid.Parse(rawImageID)
+	if err != nil {
+		return database.Image{}, ErrImageNotFound
+	}
+
+	image, err := cfg.DB.GetImageByID(ctx, imageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Image{}, ErrImageNotFound
+		}
+		return database.Image{}, err
+	}
+	if image.UserID != userID {
+		return database.Image{}, ErrImageNotFound
+	}
+	return image, nil
+}
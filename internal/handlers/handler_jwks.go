@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerGetJWKS godoc
+//
+//	@Summary		Получить публичные ключи для проверки токенов
+//	@Description	Возвращает JSON Web Key Set (RFC 7517) с публичными ключами, которыми можно проверить подпись JWT. Поле `kid` в заголовке токена указывает, каким ключом он подписан. При подписи HS256 список ключей пуст.
+//	@Produce		json
+//	@Success		200	{object}	dto.JWKSResponse	"Набор ключей"
+//	@Router			/.well-known/jwks.json [get]
+func (cfg *ApiConfig) HandlerGetJWKS(c *gin.Context) {
+	// verifiers may cache the keys, a new key is added to the set before it's used for signing
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, cfg.Keys.JWKS())
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/notify"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	passwordvalidator "github.com/wagslane/go-password-validator"
+)
+
+var (
+	ErrInvalidOldPassword        = errors.New("invalid old password")
+	ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+	ErrTooManyResetRequests      = errors.New("too many password reset requests, try again later")
+	ErrResetQueueFull            = errors.New("too many pending password reset requests, try again later")
+)
+
+// HandlerChangePassword godoc
+//
+//	@Summary		Сменить пароль
+//	@Description	Меняет пароль текущего пользователя после проверки старого пароля. Все выданные ранее токены отзываются на всех устройствах, в ответе возвращается новая пара токенов для текущего клиента. Неверный старый пароль учитывается как неудачная попытка входа.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			password		body		dto.ChangePasswordRequest	true	"Старый и новый пароли"
+//	@Success		200				{object}	dto.AuthResponse			"Пароль изменён"
+//	@Failure		400				{object}	dto.ErrorResponse			"Неверный формат запроса или новый пароль слишком слабый"
+//	@Failure		401				{object}	dto.ErrorResponse			"Отсутствует или недействителен токен доступа"
+//	@Failure		403				{object}	dto.ErrorResponse			"Неверный старый пароль"
+//	@Failure		429				{object}	dto.ErrorResponse			"Слишком много неудачных попыток, время ожидания в заголовке Retry-After"
+//	@Failure		500				{object}	dto.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/api/users/me/password [put]
+func (cfg *ApiConfig) HandlerChangePassword(c *gin.Context) {
+	input := dto.ChangePasswordRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(c.Request.Context(), auth.MustUserID(c))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	if !cfg.checkPassword(c, user, input.OldPassword, ErrInvalidOldPassword) {
+		return
+	}
+
+	if err := passwordvalidator.Validate(input.NewPassword, constants.MinEntropyBits); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	// the new password, the logout and the new session are committed together,
+	// so the old sessions can't outlive the password they were opened with
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	if err := setPassword(c.Request.Context(), qtx, user.ID, input.NewPassword); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	validAfter, err := logoutEverywhere(c.Request.Context(), qtx, user.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// the client changing the password stays logged in with a new session
+	user.TokensValidAfter = sql.NullTime{Time: validAfter, Valid: true}
+	tokens, err := cfg.issueTokens(c.Request.Context(), qtx, user, uuid.New())
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	cfg.Revocations.SetValidAfter(user.ID, validAfter)
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// HandlerRequestPasswordReset godoc
+//
+//	@Summary		Запросить сброс пароля
+//	@Description	Отправляет пользователю одноразовый токен для сброса пароля, действующий 1 час. Ранее выданные токены сброса перестают действовать. Ответ не зависит от того, существует ли логин: токен готовится и отправляется уже после ответа. Первые 3 запроса для логина (10 для IP-адреса) проходят без задержки, после каждого следующего новые запросы отклоняются на время, которое удваивается: 1 минута, 2, 4 и так далее, но не больше часа.
+//	@Accept			json
+//	@Produce		json
+//	@Param			reset	body	dto.PasswordResetRequest	true	"Логин пользователя"
+//	@Success		202		"Запрос принят"
+//	@Failure		400		{object}	dto.ErrorResponse	"Неверный формат запроса"
+//	@Failure		429		{object}	dto.ErrorResponse	"Слишком много запросов сброса, время ожидания в заголовке Retry-After"
+//	@Failure		500		{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Failure		503		{object}	dto.ErrorResponse	"Очередь отправки токенов переполнена"
+//	@Router			/api/auth/password-reset [post]
+func (cfg *ApiConfig) HandlerRequestPasswordReset(c *gin.Context) {
+	input := dto.PasswordResetRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	// every request counts, unknown logins too, so the limit doesn't reveal which logins exist
+	_, retryAfter, err := cfg.ResetGuard.Reserve(c.Request.Context(), input.Login, c.ClientIP())
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		dto.ResponseWithError(c, http.StatusTooManyRequests, ErrTooManyResetRequests.Error(), nil)
+		return
+	}
+
+	// the answer doesn't wait for the lookup and the token, so neither its content
+	// nor its timing tells whether the login exists
+	select {
+	case cfg.PasswordResets <- input.Login:
+	default:
+		dto.ResponseWithError(c, http.StatusServiceUnavailable, ErrResetQueueFull.Error(), nil)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// RunPasswordResetSender sends password reset tokens queued by HandlerRequestPasswordReset until ctx
+// is done. It's stopped after the server, so requests queued by then are still sent before it returns.
+func (cfg *ApiConfig) RunPasswordResetSender(ctx context.Context) {
+	for {
+		select {
+		case login := <-cfg.PasswordResets:
+			cfg.sendQueuedPasswordReset(login)
+		case <-ctx.Done():
+			for {
+				select {
+				case login := <-cfg.PasswordResets:
+					cfg.sendQueuedPasswordReset(login)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (cfg *ApiConfig) sendQueuedPasswordReset(login string) {
+	// a send that has started is finished even when the sender is stopping
+	if err := cfg.sendPasswordReset(context.Background(), login); err != nil {
+		log.Printf("couldn't send password reset token: %v", err)
+	}
+}
+
+// sendPasswordReset issues a new reset token for the login and sends it to the user.
+// Unknown and deleted logins are silently skipped.
+func (cfg *ApiConfig) sendPasswordReset(ctx context.Context, login string) error {
+	user, err := cfg.DB.GetUserByLogin(ctx, login)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// a new password would bring a deleted account back
+	if user.DeletedAt.Valid {
+		return nil
+	}
+
+	token, err := auth.MakePasswordResetToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	tx, err := cfg.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	// rows of expired tokens are of no use, this keeps the table small
+	if err := qtx.DeleteExpiredPasswordResetTokens(ctx, now); err != nil {
+		return err
+	}
+	// only the latest token is valid
+	err = qtx.InvalidateUserPasswordResetTokens(
+		ctx,
+		database.InvalidateUserPasswordResetTokensParams{
+			UserID: user.ID,
+			UsedAt: sql.NullTime{Time: now, Valid: true},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	err = qtx.CreatePasswordResetToken(
+		ctx,
+		database.CreatePasswordResetTokenParams{
+			UserID:    user.ID,
+			TokenHash: auth.HashPasswordResetToken(token),
+			CreatedAt: now,
+			ExpiresAt: now.Add(constants.PasswordResetLifetime),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return cfg.Notifier.Send(
+		ctx,
+		notify.Message{
+			To:      user.Login,
+			Subject: "Сброс пароля",
+			Body: fmt.Sprintf(
+				"Для сброса пароля передайте этот токен в POST /api/auth/password-reset/confirm: %s\nТокен действует до %s UTC. Если вы не запрашивали сброс пароля, проигнорируйте это сообщение.",
+				token,
+				now.Add(constants.PasswordResetLifetime).Format("2006-01-02 15:04"),
+			),
+		},
+	)
+}
+
+// HandlerConfirmPasswordReset godoc
+//
+//	@Summary		Сбросить пароль
+//	@Description	Устанавливает новый пароль по токену сброса. Токен можно использовать только один раз, все выданные ранее JWT и refresh-токены пользователя отзываются.
+//	@Accept			json
+//	@Produce		json
+//	@Param			reset	body	dto.ConfirmPasswordResetRequest	true	"Токен сброса и новый пароль"
+//	@Success		204		"Пароль изменён"
+//	@Failure		400		{object}	dto.ErrorResponse	"Неверный формат запроса, новый пароль слишком слабый, токен недействителен или истёк"
+//	@Failure		500		{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/auth/password-reset/confirm [post]
+func (cfg *ApiConfig) HandlerConfirmPasswordReset(c *gin.Context) {
+	input := dto.ConfirmPasswordResetRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+	if err := passwordvalidator.Validate(input.NewPassword, constants.MinEntropyBits); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	resetToken, err := cfg.DB.GetPasswordResetTokenByHash(c.Request.Context(), auth.HashPasswordResetToken(input.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidPasswordResetToken.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	now := time.Now().UTC()
+	if resetToken.UsedAt.Valid || !resetToken.ExpiresAt.After(now) {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidPasswordResetToken.Error(), nil)
+		return
+	}
+
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	markedRows, err := qtx.MarkPasswordResetTokenUsed(
+		c.Request.Context(),
+		database.MarkPasswordResetTokenUsedParams{
+			ID:     resetToken.ID,
+			UsedAt: sql.NullTime{Time: now, Valid: true},
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if markedRows == 0 {
+		// the token was used by a concurrent request
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidPasswordResetToken.Error(), nil)
+		return
+	}
+	if err := setPassword(c.Request.Context(), qtx, resetToken.UserID, input.NewPassword); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	user, err := qtx.GetUserByID(c.Request.Context(), resetToken.UserID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	validAfter, err := logoutEverywhere(c.Request.Context(), qtx, user.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	cfg.Revocations.SetValidAfter(user.ID, validAfter)
+
+	// failed guesses of the old password shouldn't lock the owner out with the new one
+	if err := cfg.LoginGuard.Reset(c.Request.Context(), user.Login); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setPassword stores the new password and invalidates pending password reset tokens.
+// Callers log the user out everywhere in the same transaction.
+func setPassword(ctx context.Context, db *database.Queries, userID uuid.UUID, password string) error {
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = db.UpdateUserPassword(
+		ctx,
+		database.UpdateUserPasswordParams{
+			ID:             userID,
+			HashedPassword: hashedPassword,
+			UpdatedAt:      now,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return db.InvalidateUserPasswordResetTokens(
+		ctx,
+		database.InvalidateUserPasswordResetTokensParams{
+			UserID: userID,
+			UsedAt: sql.NullTime{Time: now, Valid: true},
+		},
+	)
+}
@@ -11,6 +11,13 @@ import (
 	"github.com/englandrecoil/go-marketplace-service/internal/constants"
 	"github.com/englandrecoil/go-marketplace-service/internal/database"
 	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/fetcher"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecache"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecheck"
+	"github.com/englandrecoil/go-marketplace-service/internal/loginguard"
+	"github.com/englandrecoil/go-marketplace-service/internal/notify"
+	"github.com/englandrecoil/go-marketplace-service/internal/revocation"
+	"github.com/englandrecoil/go-marketplace-service/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 	passwordvalidator "github.com/wagslane/go-password-validator"
@@ -22,9 +29,24 @@ var (
 )
 
 type ApiConfig struct {
-	Conn   *sql.DB
-	DB     *database.Queries
-	Secret string
+	Conn           *sql.DB
+	DB             *database.Queries
+	Secret         string
+	Keys           *auth.KeySet
+	Storage        storage.Storage
+	Fetcher        *fetcher.Fetcher
+	ImagePolicy    imagecheck.Policy
+	ImageCache     *imagecache.Cache
+	ProxyFetches   *ProxyFetches
+	ImageChecker   ImageCheckerConfig
+	AccountPurge   AccountPurgeConfig
+	Revocations    *revocation.Store
+	Auth           *auth.Authenticator
+	LoginGuard     *loginguard.Guard
+	ResetGuard     *loginguard.Guard
+	Notifier       notify.Notifier
+	PasswordResets chan string
+	TrustedProxies []string
 }
 
 // HandlerRegister godoc
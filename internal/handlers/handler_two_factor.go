@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/englandrecoil/go-marketplace-service/internal/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPassword         = errors.New("invalid password")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken         = errors.New("invalid or expired mfa token")
+	ErrMFATokenExhausted       = errors.New("too many attempts with this mfa token, log in again")
+	ErrMFATokenUsed            = errors.New("mfa token has already been used, log in again")
+	ErrTwoFactorEnabled        = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorEnrollmentUsed = errors.New("two-factor enrollment has already been confirmed")
+)
+
+// HandlerEnrollTOTP godoc
+//
+//	@Summary		Начать подключение двухфакторной аутентификации
+//	@Description	Создаёт секрет TOTP (RFC 6238) и возвращает его вместе с otpauth:// URI для приложения-аутентификатора. Двухфакторная аутентификация включится только после подтверждения кодом из приложения. Повторный вызов до подтверждения заменяет секрет. Неверный пароль учитывается как неудачная попытка входа.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			password		body		dto.EnrollTOTPRequest		true	"Текущий пароль"
+//	@Success		200				{object}	dto.TOTPEnrollmentResponse	"Секрет для приложения-аутентификатора"
+//	@Failure		400				{object}	dto.ErrorResponse			"Неверный формат запроса"
+//	@Failure		401				{object}	dto.ErrorResponse			"Отсутствует или недействителен токен доступа"
+//	@Failure		403				{object}	dto.ErrorResponse			"Неверный пароль"
+//	@Failure		409				{object}	dto.ErrorResponse			"Двухфакторная аутентификация уже включена"
+//	@Failure		429				{object}	dto.ErrorResponse			"Слишком много неудачных попыток, время ожидания в заголовке Retry-After"
+//	@Failure		500				{object}	dto.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/api/users/me/2fa/totp [post]
+func (cfg *ApiConfig) HandlerEnrollTOTP(c *gin.Context) {
+	input := dto.EnrollTOTPRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(c.Request.Context(), auth.MustUserID(c))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	// otherwise a stolen access token could lock the owner out with the thief's authenticator
+	if !cfg.checkPassword(c, user, input.Password, ErrInvalidPassword) {
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	createdRows, err := cfg.DB.CreateUserTOTP(
+		c.Request.Context(),
+		database.CreateUserTOTPParams{
+			UserID:    user.ID,
+			Secret:    secret,
+			CreatedAt: time.Now().UTC(),
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if createdRows == 0 {
+		dto.ResponseWithError(c, http.StatusConflict, ErrTwoFactorEnabled.Error(), nil)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		dto.TOTPEnrollmentResponse{
+			Secret: secret,
+			URI:    totp.URI(constants.TOTPIssuer, user.Login, secret),
+		},
+	)
+}
+
+// HandlerConfirmTOTP godoc
+//
+//	@Summary		Подтвердить подключение двухфакторной аутентификации
+//	@Description	Включает двухфакторную аутентификацию, если код из приложения-аутентификатора верный, и возвращает одноразовые коды восстановления. Коды показываются только один раз, каждый из них можно использовать вместо кода из приложения.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			code			body		dto.ConfirmTOTPRequest		true	"Код из приложения-аутентификатора"
+//	@Success		200				{object}	dto.RecoveryCodesResponse	"Двухфакторная аутентификация включена"
+//	@Failure		400				{object}	dto.ErrorResponse			"Неверный формат запроса или неверный код"
+//	@Failure		401				{object}	dto.ErrorResponse			"Отсутствует или недействителен токен доступа"
+//	@Failure		409				{object}	dto.ErrorResponse			"Подключение не начато или уже подтверждено"
+//	@Failure		500				{object}	dto.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/api/users/me/2fa/totp/confirm [post]
+func (cfg *ApiConfig) HandlerConfirmTOTP(c *gin.Context) {
+	input := dto.ConfirmTOTPRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+	userID := auth.MustUserID(c)
+
+	userTOTP, err := cfg.DB.GetUserTOTP(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusConflict, ErrTwoFactorNotEnrolled.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if userTOTP.ConfirmedAt.Valid {
+		dto.ResponseWithError(c, http.StatusConflict, ErrTwoFactorEnrollmentUsed.Error(), nil)
+		return
+	}
+	now := time.Now().UTC()
+	step, ok := totp.Validate(userTOTP.Secret, input.Code, now)
+	if !ok {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrInvalidTwoFactorCode.Error(), nil)
+		return
+	}
+
+	recoveryCodes, err := auth.MakeRecoveryCodes(constants.RecoveryCodesCount)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	confirmedRows, err := qtx.ConfirmUserTOTP(
+		c.Request.Context(),
+		database.ConfirmUserTOTPParams{
+			UserID:       userID,
+			ConfirmedAt:  sql.NullTime{Time: now, Valid: true},
+			LastUsedStep: step,
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if confirmedRows == 0 {
+		// confirmed by a concurrent request or enrollment was restarted
+		dto.ResponseWithError(c, http.StatusConflict, ErrTwoFactorEnrollmentUsed.Error(), nil)
+		return
+	}
+	if err := qtx.DeleteUserRecoveryCodes(c.Request.Context(), userID); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	for _, code := range recoveryCodes {
+		err := qtx.CreateRecoveryCode(
+			c.Request.Context(),
+			database.CreateRecoveryCodeParams{
+				UserID:    userID,
+				CodeHash:  auth.HashRecoveryCode(code),
+				CreatedAt: now,
+			},
+		)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// HandlerDisableTOTP godoc
+//
+//	@Summary		Отключить двухфакторную аутентификацию
+//	@Description	Отключает двухфакторную аутентификацию и удаляет коды восстановления. Нужны текущий пароль и код из приложения-аутентификатора или код восстановления.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header	string					true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			confirmation	body	dto.DisableTOTPRequest	true	"Пароль и код"
+//	@Success		204				"Двухфакторная аутентификация отключена"
+//	@Failure		400				{object}	dto.ErrorResponse	"Неверный формат запроса"
+//	@Failure		401				{object}	dto.ErrorResponse	"Отсутствует или недействителен токен доступа"
+//	@Failure		403				{object}	dto.ErrorResponse	"Неверный пароль или код"
+//	@Failure		409				{object}	dto.ErrorResponse	"Двухфакторная аутентификация не включена"
+//	@Failure		429				{object}	dto.ErrorResponse	"Слишком много неудачных попыток, время ожидания в заголовке Retry-After"
+//	@Failure		500				{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/users/me/2fa/totp [delete]
+func (cfg *ApiConfig) HandlerDisableTOTP(c *gin.Context) {
+	input := dto.DisableTOTPRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(c.Request.Context(), auth.MustUserID(c))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	twoFactor, err := cfg.twoFactorEnabled(c.Request.Context(), user.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if !twoFactor {
+		dto.ResponseWithError(c, http.StatusConflict, ErrTwoFactorNotEnabled.Error(), nil)
+		return
+	}
+	if !cfg.checkPassword(c, user, input.Password, ErrInvalidPassword) {
+		return
+	}
+	if !cfg.checkSecondFactor(c, user, input.Code, http.StatusForbidden) {
+		return
+	}
+
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	if err := qtx.DeleteUserTOTP(c.Request.Context(), user.ID); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := qtx.DeleteUserRecoveryCodes(c.Request.Context(), user.ID); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandlerVerifyMFA godoc
+//
+//	@Summary		Завершить вход вторым фактором
+//	@Description	Обменивает `mfa_token`, полученный в `POST /api/auth`, и код из приложения-аутентификатора или код восстановления на JWT и refresh-токен. `mfa_token` действует 5 минут, принимает не больше 5 попыток и обменивается на токены только один раз, после этого нужно снова войти с паролем. Неверный код учитывается как неудачная попытка входа.
+//	@Accept			json
+//	@Produce		json
+//	@Param			mfa	body		dto.MFARequest		true	"Токен первого шага и код"
+//	@Success		200	{object}	dto.AuthResponse	"Успешная аутентификация"
+//	@Failure		400	{object}	dto.ErrorResponse	"Неверный формат запроса"
+//	@Failure		401	{object}	dto.ErrorResponse	"Недействительный, исчерпанный или уже использованный mfa_token или неверный код"
+//	@Failure		429	{object}	dto.ErrorResponse	"Слишком много неудачных попыток входа, время ожидания в заголовке Retry-After"
+//	@Failure		500	{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/auth/mfa [post]
+func (cfg *ApiConfig) HandlerVerifyMFA(c *gin.Context) {
+	input := dto.MFARequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	userID, tokenID, err := auth.ValidateMFAToken(input.MFAToken, cfg.Keys)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusUnauthorized, ErrInvalidMFAToken.Error(), err)
+		return
+	}
+	// the login counter is shared with other clients, the token itself only gets a few codes
+	allowed, err := cfg.LoginGuard.ReserveToken(c.Request.Context(), tokenID, constants.MFATokenMaxAttempts)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if !allowed {
+		dto.ResponseWithError(c, http.StatusUnauthorized, ErrMFATokenExhausted.Error(), nil)
+		return
+	}
+	user, err := cfg.DB.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusUnauthorized, ErrInvalidMFAToken.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	if !cfg.checkSecondFactor(c, user, input.Code, http.StatusUnauthorized) {
+		return
+	}
+	// one password login gives one session, the token is no use after the exchange
+	firstUse, err := cfg.LoginGuard.UseToken(c.Request.Context(), tokenID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if !firstUse {
+		dto.ResponseWithError(c, http.StatusUnauthorized, ErrMFATokenUsed.Error(), nil)
+		return
+	}
+	if err := cfg.LoginGuard.Reset(c.Request.Context(), user.Login); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// every login starts a new family of refresh tokens
+	tokens, err := cfg.issueTokens(c.Request.Context(), cfg.DB, user, uuid.New())
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// twoFactorEnabled reports whether the user confirmed a TOTP enrollment.
+func (cfg *ApiConfig) twoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	userTOTP, err := cfg.DB.GetUserTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return userTOTP.ConfirmedAt.Valid, nil
+}
+
+// checkSecondFactor accepts a code from the authenticator app or a recovery code, each of them
+// only once. Wrong codes count as failed logins, 6 digits are guessed way faster than passwords.
+// On a wrong code it responds with wrongCodeStatus, it returns whether to go on.
+func (cfg *ApiConfig) checkSecondFactor(c *gin.Context, user database.User, code string, wrongCodeStatus int) bool {
+	attempt, retryAfter, err := cfg.LoginGuard.Reserve(c.Request.Context(), user.Login, c.ClientIP())
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return false
+	}
+	if retryAfter > 0 {
+		respondTooManyAttempts(c, retryAfter)
+		return false
+	}
+
+	ok, err := cfg.useSecondFactor(c.Request.Context(), user.ID, code)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return false
+	}
+	if !ok {
+		dto.ResponseWithError(c, wrongCodeStatus, ErrInvalidTwoFactorCode.Error(), nil)
+		return false
+	}
+	if err := cfg.LoginGuard.Release(c.Request.Context(), attempt); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return false
+	}
+	return true
+}
+
+func (cfg *ApiConfig) useSecondFactor(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	if len(code) != totp.Digits {
+		usedRows, err := cfg.DB.UseRecoveryCode(
+			ctx,
+			database.UseRecoveryCodeParams{
+				UserID:   userID,
+				CodeHash: auth.HashRecoveryCode(code),
+				UsedAt:   sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			},
+		)
+		return usedRows == 1, err
+	}
+
+	userTOTP, err := cfg.DB.GetUserTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	step, ok := totp.Validate(userTOTP.Secret, code, time.Now().UTC())
+	if !ok || !userTOTP.ConfirmedAt.Valid {
+		return false, nil
+	}
+	// a code is accepted once, the update fails for steps at or before the last accepted one
+	usedRows, err := cfg.DB.UseUserTOTPStep(
+		ctx,
+		database.UseUserTOTPStepParams{
+			UserID:       userID,
+			LastUsedStep: step,
+		},
+	)
+	return usedRows == 1, err
+}
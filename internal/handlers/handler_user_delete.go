@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTwoFactorCodeRequired = errors.New("two-factor code is required")
+	ErrLastAdmin             = errors.New("the only admin can't delete the account")
+)
+
+// HandlerDeleteAccount godoc
+//
+//	@Summary		Удалить аккаунт
+//	@Description	Удаляет аккаунт текущего пользователя после подтверждения паролем, при включённой двухфакторной аутентификации нужен ещё и код. Логин освобождается, профиль и двухфакторная аутентификация удаляются, все объявления переносятся в архив, все токены отзываются. Сами данные окончательно удаляются фоновой задачей по истечении срока хранения.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header	string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			confirmation	body	dto.DeleteAccountRequest	true	"Пароль и, при включённой двухфакторной аутентификации, код"
+//	@Success		204				"Аккаунт удалён"
+//	@Failure		400				{object}	dto.ErrorResponse	"Неверный формат запроса или не передан код"
+//	@Failure		401				{object}	dto.ErrorResponse	"Отсутствует или недействителен токен доступа"
+//	@Failure		403				{object}	dto.ErrorResponse	"Неверный пароль или код"
+//	@Failure		409				{object}	dto.ErrorResponse	"Пользователь — единственный администратор"
+//	@Failure		429				{object}	dto.ErrorResponse	"Слишком много неудачных попыток, время ожидания в заголовке Retry-After"
+//	@Failure		500				{object}	dto.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/api/users/me [delete]
+func (cfg *ApiConfig) HandlerDeleteAccount(c *gin.Context) {
+	input := dto.DeleteAccountRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(c.Request.Context(), auth.MustUserID(c))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	twoFactor, err := cfg.twoFactorEnabled(c.Request.Context(), user.ID)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if twoFactor && input.Code == "" {
+		dto.ResponseWithError(c, http.StatusBadRequest, ErrTwoFactorCodeRequired.Error(), nil)
+		return
+	}
+	if !cfg.checkPassword(c, user, input.Password, ErrInvalidPassword) {
+		return
+	}
+	if twoFactor && !cfg.checkSecondFactor(c, user, input.Code, http.StatusForbidden) {
+		return
+	}
+
+	now := time.Now().UTC()
+	tx, err := cfg.Conn.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	if user.Role == auth.RoleAdmin {
+		// the admins stay locked until the commit, so two of them can't delete their accounts at once
+		admins, err := qtx.LockUsersWithRole(c.Request.Context(), auth.RoleAdmin)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+		if len(admins) <= 1 {
+			dto.ResponseWithError(c, http.StatusConflict, ErrLastAdmin.Error(), nil)
+			return
+		}
+	}
+
+	// ads stay in the database until the purge, but nobody sees archived ads except their owner
+	err = qtx.ArchiveUserAdvertisements(
+		c.Request.Context(),
+		database.ArchiveUserAdvertisementsParams{
+			UserID:    user.ID,
+			UpdatedAt: now,
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := qtx.DeleteUserTOTP(c.Request.Context(), user.ID); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := qtx.DeleteUserRecoveryCodes(c.Request.Context(), user.ID); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	err = qtx.InvalidateUserPasswordResetTokens(
+		c.Request.Context(),
+		database.InvalidateUserPasswordResetTokensParams{
+			UserID: user.ID,
+			UsedAt: sql.NullTime{Time: now, Valid: true},
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	// finished exports go with the next cleanup, the user can't download them anymore anyway
+	err = qtx.ExpireUserDataExports(
+		c.Request.Context(),
+		database.ExpireUserDataExportsParams{
+			UserID:    user.ID,
+			ExpiresAt: sql.NullTime{Time: now, Valid: true},
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	err = qtx.RevokeUserRefreshTokens(
+		c.Request.Context(),
+		database.RevokeUserRefreshTokensParams{
+			UserID:    user.ID,
+			RevokedAt: sql.NullTime{Time: now, Valid: true},
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	// access tokens are rejected in the same transaction, so a deleted account can't be used even if the request fails later
+	err = qtx.AnonymizeUser(
+		c.Request.Context(),
+		database.AnonymizeUserParams{
+			ID:               user.ID,
+			Login:            deletedLogin(user.ID),
+			TokensValidAfter: sql.NullTime{Time: now, Valid: true},
+			DeletedAt:        sql.NullTime{Time: now, Valid: true},
+			UpdatedAt:        now,
+		},
+	)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// the account is gone already, this only saves waiting for the revocation cache to expire
+	cfg.Revocations.SetValidAfter(user.ID, now)
+	// the login is free now, whoever takes it shouldn't inherit failed attempts
+	if err := cfg.LoginGuard.Reset(c.Request.Context(), user.Login); err != nil {
+		log.Printf("couldn't reset login failures of deleted user %s: %v", user.ID, err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// deletedLogin replaces the login of a deleted user. It's longer than any login allowed at
+// registration, so it can't be taken by someone else.
+func deletedLogin(userID uuid.UUID) string {
+	return "deleted-" + strings.ReplaceAll(userID.String(), "-", "")
+}
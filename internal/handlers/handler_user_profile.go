@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/constants"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound                = errors.New("user not found")
+	ErrInvalidLengthDisplayName    = errors.New("invalid display name length")
+	ErrInvalidLengthBio            = errors.New("invalid bio length")
+	ErrInvalidLengthCity           = errors.New("invalid city length")
+	ErrInvalidLengthContact        = errors.New("invalid contact length")
+	ErrInvalidContactEmail         = errors.New("invalid contact email")
+	ErrInvalidPreferredContact     = errors.New("preferred contact must be one of chat, phone, email, telegram")
+	ErrMissingPreferredContactInfo = errors.New("preferred contact is not filled in")
+)
+
+// HandlerGetMyProfile godoc
+//
+//	@Summary		Получить свой профиль
+//	@Description	Возвращает профиль текущего пользователя вместе с контактами, даже если они скрыты от других
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string					true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Success		200				{object}	dto.UserProfileResponse	"Профиль пользователя"
+//	@Failure		401				{object}	dto.ErrorResponse		"Отсутствует или недействителен токен доступа"
+//	@Failure		500				{object}	dto.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/api/users/me [get]
+func (cfg *ApiConfig) HandlerGetMyProfile(c *gin.Context) {
+	user, err := cfg.DB.GetUserByID(c.Request.Context(), auth.MustUserID(c))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	response, err := cfg.userProfileResponse(c.Request.Context(), user)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HandlerUpdateMyProfile godoc
+//
+//	@Summary		Обновить свой профиль
+//	@Description	Изменяет переданные поля профиля, остальные остаются прежними. Пустая строка очищает поле. Аватар задаётся через `avatar_image_id` изображения, загруженного через `POST /api/images`. `contact_preferences` заменяются целиком, другим пользователям они показываются только при `public: true`.
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Authorization	header		string						true	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			profile			body		dto.UpdateProfileRequest	true	"Поля профиля"
+//	@Success		200				{object}	dto.UserProfileResponse		"Обновлённый профиль"
+//	@Failure		400				{object}	dto.ErrorResponse			"Неверный формат запроса или недопустимые значения полей"
+//	@Failure		401				{object}	dto.ErrorResponse			"Отсутствует или недействителен токен доступа"
+//	@Failure		500				{object}	dto.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/api/users/me [patch]
+func (cfg *ApiConfig) HandlerUpdateMyProfile(c *gin.Context) {
+	input := dto.UpdateProfileRequest{}
+	if err := c.BindJSON(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid request body format", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(c.Request.Context(), auth.MustUserID(c))
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	params := database.UpdateUserProfileParams{
+		ID:                 user.ID,
+		DisplayName:        user.DisplayName,
+		AvatarImageID:      user.AvatarImageID,
+		Bio:                user.Bio,
+		City:               user.City,
+		ContactPreferences: user.ContactPreferences,
+		UpdatedAt:          time.Now().UTC(),
+	}
+	if input.DisplayName != nil {
+		params.DisplayName, err = profileField(*input.DisplayName, constants.MaxDisplayNameLength, ErrInvalidLengthDisplayName)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	if input.Bio != nil {
+		params.Bio, err = profileField(*input.Bio, constants.MaxBioLength, ErrInvalidLengthBio)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	if input.City != nil {
+		params.City, err = profileField(*input.City, constants.MaxCityLength, ErrInvalidLengthCity)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	if input.AvatarImageID != nil {
+		params.AvatarImageID = uuid.NullUUID{}
+		if *input.AvatarImageID != "" {
+			image, err := cfg.getOwnedImage(c.Request.Context(), user.ID, *input.AvatarImageID)
+			if err != nil {
+				if errors.Is(err, ErrImageNotFound) {
+					dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+					return
+				}
+				dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+				return
+			}
+			params.AvatarImageID = uuid.NullUUID{UUID: image.ID, Valid: true}
+		}
+	}
+	if input.ContactPreferences != nil {
+		preferences := *input.ContactPreferences
+		if err := validateContactPreferences(&preferences); err != nil {
+			dto.ResponseWithError(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		params.ContactPreferences, err = json.Marshal(preferences)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+	}
+
+	user, err = cfg.DB.UpdateUserProfile(c.Request.Context(), params)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	response, err := cfg.userProfileResponse(c.Request.Context(), user)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HandlerGetUserProfile godoc
+//
+//	@Summary		Получить страницу продавца
+//	@Description	Возвращает публичный профиль пользователя, количество его опубликованных объявлений и сами объявления с той же пагинацией, что и `GET /api/ads`. Контакты возвращаются, только если пользователь сделал их публичными.
+//	@Produce		json
+//	@Param			Authorization	header		string							false	"Bearer токен"	example(Bearer J2bc3Cd0F...)
+//	@Param			login			path		string							true	"Логин пользователя"
+//	@Param			page			query		int								false	"Номер страницы"						default(1)	minimum(1)
+//	@Param			cursor			query		string							false	"Курсор next_cursor из предыдущего ответа, при его наличии page игнорируется"
+//	@Param			page_size		query		int								false	"Количество возвращаемых объявлений"	default(25)	minimum(1)	maximum(100)
+//	@Param			sort_by			query		string							false	"Поле для сортировки"					default(created_at)	Enums(price, created_at)
+//	@Param			order			query		string							false	"Направление сортировки"				default(desc)		Enums(asc, desc)
+//	@Success		200				{object}	dto.PublicUserProfileResponse	"Страница продавца"
+//	@Header			200				{string}	Link							"Ссылки на соседние страницы объявлений (RFC 8288)"
+//	@Failure		400				{object}	dto.ErrorResponse				"Неверные параметры запроса"
+//	@Failure		401				{object}	dto.ErrorResponse				"Невалидный или просроченный токен-доступа"
+//	@Failure		404				{object}	dto.ErrorResponse				"Пользователь не найден"
+//	@Failure		500				{object}	dto.ErrorResponse				"Внутренняя ошибка сервера"
+//	@Router			/api/users/{login} [get]
+func (cfg *ApiConfig) HandlerGetUserProfile(c *gin.Context) {
+	// the route uses OptionalAuth: the seller also sees own ads hidden because of a dead image
+	viewerID, _ := auth.UserID(c)
+
+	input := dto.UserAdsQueryParamsRequest{}
+	if err := c.BindQuery(&input); err != nil {
+		dto.ResponseWithError(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByLogin(c.Request.Context(), c.Param("login"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.ResponseWithError(c, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+			return
+		}
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if user.DeletedAt.Valid {
+		dto.ResponseWithError(c, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+		return
+	}
+
+	// the total of the list is the active ad count, so it's always requested
+	adsParams := database.GetAdvertisementsParams{
+		MinPrice:      constants.MinPrice,
+		MaxPrice:      constants.MaxPrice,
+		Status:        AdStatusPublished,
+		UserID:        uuid.NullUUID{UUID: user.ID, Valid: true},
+		IncludeBroken: viewerID == user.ID,
+	}
+	query := dto.GetAdsQueryParamsRequest{
+		Page:      input.Page,
+		Cursor:    input.Cursor,
+		PageSize:  input.PageSize,
+		SortBy:    input.SortBy,
+		Order:     input.Order,
+		WithTotal: true,
+	}
+	normalizeAdsPage(&query)
+	ads, ok := cfg.listAds(c, query, adsParams, viewerID)
+	if !ok {
+		return
+	}
+	// the seller's list includes ads hidden from others, the count is the same for everyone
+	activeAdsCount := *ads.Total
+	if adsParams.IncludeBroken {
+		activeAdsCount, err = cfg.DB.CountAdvertisements(
+			c.Request.Context(),
+			database.CountAdvertisementsParams{
+				MinPrice:      adsParams.MinPrice,
+				MaxPrice:      adsParams.MaxPrice,
+				Status:        adsParams.Status,
+				UserID:        adsParams.UserID,
+				IncludeBroken: false,
+			},
+		)
+		if err != nil {
+			dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+	}
+
+	profile, err := cfg.userProfileResponse(c.Request.Context(), user)
+	if err != nil {
+		dto.ResponseWithError(c, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	response := dto.PublicUserProfileResponse{
+		Login:          profile.Login,
+		DisplayName:    profile.DisplayName,
+		AvatarURL:      profile.AvatarURL,
+		AvatarVariants: profile.AvatarVariants,
+		Bio:            profile.Bio,
+		City:           profile.City,
+		RegisteredAt:   user.CreatedAt,
+		ActiveAdsCount: activeAdsCount,
+		Ads:            ads,
+	}
+	if profile.ContactPreferences.Public {
+		response.Contacts = &profile.ContactPreferences
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (cfg *ApiConfig) userProfileResponse(ctx context.Context, user database.User) (dto.UserProfileResponse, error) {
+	response := dto.UserProfileResponse{
+		ID:          user.ID,
+		Login:       user.Login,
+		Role:        user.Role,
+		DisplayName: user.DisplayName.String,
+		Bio:         user.Bio.String,
+		City:        user.City.String,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+	}
+	if len(user.ContactPreferences) > 0 {
+		if err := json.Unmarshal(user.ContactPreferences, &response.ContactPreferences); err != nil {
+			return dto.UserProfileResponse{}, err
+		}
+	}
+	if user.AvatarImageID.Valid {
+		image, err := cfg.DB.GetImageByID(ctx, user.AvatarImageID.UUID)
+		if err != nil {
+			return dto.UserProfileResponse{}, err
+		}
+		response.AvatarImageID = &image.ID
+		response.AvatarURL = imageURL(image.ID)
+		response.AvatarVariants = imageVariantsResponse(image.ID, image.VariantWidths)
+	}
+	return response, nil
+}
+
+// profileField trims the value of a free-form profile field, an empty value clears the field.
+func profileField(value string, maxLength int, lengthErr error) (sql.NullString, error) {
+	value = strings.TrimSpace(value)
+	if utf8.RuneCountInString(value) > maxLength {
+		return sql.NullString{}, lengthErr
+	}
+	return sql.NullString{String: value, Valid: value != ""}, nil
+}
+
+// validateContactPreferences trims the contacts and checks the preferred one is filled in.
+func validateContactPreferences(preferences *dto.ContactPreferences) error {
+	preferences.Phone = strings.TrimSpace(preferences.Phone)
+	preferences.Email = strings.TrimSpace(preferences.Email)
+	preferences.Telegram = strings.TrimSpace(preferences.Telegram)
+	for _, contact := range []string{preferences.Phone, preferences.Email, preferences.Telegram} {
+		if utf8.RuneCountInString(contact) > constants.MaxContactLength {
+			return ErrInvalidLengthContact
+		}
+	}
+	if preferences.Email != "" {
+		if address, err := mail.ParseAddress(preferences.Email); err != nil || address.Address != preferences.Email {
+			return ErrInvalidContactEmail
+		}
+	}
+
+	switch preferences.Preferred {
+	case "", "chat":
+		return nil
+	case "phone":
+		if preferences.Phone == "" {
+			return ErrMissingPreferredContactInfo
+		}
+	case "email":
+		if preferences.Email == "" {
+			return ErrMissingPreferredContactInfo
+		}
+	case "telegram":
+		if preferences.Telegram == "" {
+			return ErrMissingPreferredContactInfo
+		}
+	default:
+		return ErrInvalidPreferredContact
+	}
+	return nil
+}
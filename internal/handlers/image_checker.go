@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+)
+
+// ImageCheckerConfig controls periodic re-validation of remote images of published ads.
+type ImageCheckerConfig struct {
+	// Interval is the pause between passes, every image is checked once per pass
+	Interval time.Duration
+	// HideAfter is how long an image may stay unreachable before its ad is hidden from the feed
+	HideAfter   time.Duration
+	Concurrency int
+	BatchSize   int
+}
+
+// RunImageChecker re-validates remote images until ctx is cancelled. Uploaded images are
+// served by us and aren't checked.
+func (cfg *ApiConfig) RunImageChecker(ctx context.Context) {
+	ticker := time.NewTicker(cfg.ImageChecker.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := cfg.checkAdImages(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			log.Printf("couldn't check ad images: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAdImages checks every image that hasn't been checked since the pass started.
+func (cfg *ApiConfig) checkAdImages(ctx context.Context, startedAt time.Time) error {
+	for {
+		ads, err := cfg.DB.GetAdsForImageCheck(
+			ctx,
+			database.GetAdsForImageCheckParams{
+				ImageCheckedAt: sql.NullTime{Time: startedAt, Valid: true},
+				Limit:          int32(cfg.ImageChecker.BatchSize),
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		// a failed write would return the same ad again, so the pass stops on it
+		err = forEachLimited(ads, cfg.ImageChecker.Concurrency, func(ad database.GetAdsForImageCheckRow) error {
+			return cfg.checkAdImage(ctx, ad)
+		})
+		if err != nil {
+			return err
+		}
+		if len(ads) < cfg.ImageChecker.BatchSize {
+			return nil
+		}
+	}
+}
+
+func (cfg *ApiConfig) checkAdImage(ctx context.Context, ad database.GetAdsForImageCheckRow) error {
+	checkErr := cfg.validateImage(ctx, ad.ImageAddress)
+	if ctx.Err() != nil {
+		// shutting down, the image isn't to blame
+		return ctx.Err()
+	}
+
+	checkedAt := time.Now().UTC()
+	if checkErr == nil {
+		return cfg.DB.RecordImageCheckSuccess(
+			ctx,
+			database.RecordImageCheckSuccessParams{
+				ID:             ad.ID,
+				ImageCheckedAt: sql.NullTime{Time: checkedAt, Valid: true},
+			},
+		)
+	}
+	return cfg.DB.RecordImageCheckFailure(
+		ctx,
+		database.RecordImageCheckFailureParams{
+			ID:              ad.ID,
+			ImageCheckedAt:  sql.NullTime{Time: checkedAt, Valid: true},
+			ImageCheckError: sql.NullString{String: checkErr.Error(), Valid: true},
+			BrokenSince:     checkedAt.Add(-cfg.ImageChecker.HideAfter),
+		},
+	)
+}
+
+// forEachLimited calls fn for every item with at most limit calls running at once
+// and returns errors of all failed calls.
+func forEachLimited[T any](items []T, limit int, fn func(T) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	semaphore := make(chan struct{}, limit)
+	errs := make([]error, len(items))
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			errs[i] = fn(item)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
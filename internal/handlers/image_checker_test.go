@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachLimited(t *testing.T) {
+	errBroken := errors.New("broken")
+
+	tests := map[string]struct {
+		items     []int
+		limit     int
+		failOn    int
+		wantCalls int64
+		wantErr   error
+	}{
+		"all_succeed":    {items: []int{1, 2, 3, 4, 5, 6, 7, 8}, limit: 3, wantCalls: 8},
+		"one_fails":      {items: []int{1, 2, 3, 4}, limit: 2, failOn: 3, wantCalls: 4, wantErr: errBroken},
+		"zero_limit":     {items: []int{1, 2}, limit: 0, wantCalls: 2},
+		"more_than_work": {items: []int{1}, limit: 10, wantCalls: 1},
+		"nothing_to_do":  {items: nil, limit: 4, wantCalls: 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls, running, maxRunning atomic.Int64
+			err := forEachLimited(tc.items, tc.limit, func(item int) error {
+				calls.Add(1)
+				current := running.Add(1)
+				defer running.Add(-1)
+				for {
+					seen := maxRunning.Load()
+					if current <= seen || maxRunning.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				if item == tc.failOn {
+					return errBroken
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if calls.Load() != tc.wantCalls {
+				t.Fatalf("%s: expected calls: %d, got: %d", name, tc.wantCalls, calls.Load())
+			}
+			if maxRunning.Load() > int64(max(tc.limit, 1)) {
+				t.Fatalf("%s: expected at most %d running calls, got: %d", name, max(tc.limit, 1), maxRunning.Load())
+			}
+		})
+	}
+}
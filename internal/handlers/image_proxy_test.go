@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/fetcher"
+	"github.com/englandrecoil/go-marketplace-service/internal/imagecheck"
+)
+
+func TestFetchRemoteImage(t *testing.T) {
+	pngImage := encodeTestImage(t, png.Encode, 40, 30)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/avatar.png":
+			// declared type is ignored, the content decides
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(pngImage)
+		case "/page.html":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("<script>alert(1)</script>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := ApiConfig{
+		Fetcher:     fetcher.New(fetcher.Options{Timeout: time.Second, MaxRedirects: 3, AllowPrivate: true}),
+		ImagePolicy: imagecheck.Policy{AllowedFormats: imagecheck.DefaultFormats, MaxWidth: 100, MaxHeight: 100},
+	}
+	tests := map[string]struct {
+		url             string
+		wantContentType string
+		containsErr     bool
+		wantErr         error
+	}{
+		"image":        {url: server.URL + "/avatar.png", wantContentType: "image/png"},
+		"html_as_png":  {url: server.URL + "/page.html", containsErr: true, wantErr: ErrInvalidImageFormat},
+		"host_missing": {url: server.URL + "/missing.png", containsErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, contentType, err := cfg.fetchRemoteImage(context.Background(), tc.url)
+			if (err != nil) != tc.containsErr {
+				t.Fatalf("%s: expected error: %v, got: %v", name, tc.containsErr, err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if contentType != tc.wantContentType || !bytes.Equal(data, pngImage) {
+				t.Fatalf("%s: expected %s image, got: %s", name, tc.wantContentType, contentType)
+			}
+		})
+	}
+}
+
+func TestProxyFetches(t *testing.T) {
+	errDown := errors.New("host is down")
+
+	t.Run("concurrent_requests_share_download", func(t *testing.T) {
+		fetches := NewProxyFetches(time.Minute)
+		release := make(chan struct{})
+		var downloads atomic.Int32
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				fetches.Do("key", func() error {
+					downloads.Add(1)
+					<-release
+					return nil
+				})
+			}()
+		}
+		// let every goroutine join the download before it finishes
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if downloads.Load() != 1 {
+			t.Fatalf("expected: %d downloads, got: %d", 1, downloads.Load())
+		}
+	})
+
+	t.Run("failure_is_remembered", func(t *testing.T) {
+		fetches := NewProxyFetches(time.Minute)
+		downloads := 0
+		download := func() error {
+			downloads++
+			return errDown
+		}
+
+		for range 3 {
+			if err := fetches.Do("key", download); !errors.Is(err, errDown) {
+				t.Fatalf("expected: %v, got: %v", errDown, err)
+			}
+		}
+		if downloads != 1 {
+			t.Fatalf("expected: %d downloads, got: %d", 1, downloads)
+		}
+		// other keys are unaffected
+		if err := fetches.Do("other", func() error { return nil }); err != nil {
+			t.Fatalf("expected: %v, got: %v", nil, err)
+		}
+	})
+
+	t.Run("failure_expires", func(t *testing.T) {
+		fetches := NewProxyFetches(10 * time.Millisecond)
+		fetches.Do("key", func() error { return errDown })
+		time.Sleep(20 * time.Millisecond)
+
+		if err := fetches.Do("key", func() error { return nil }); err != nil {
+			t.Fatalf("expected: %v, got: %v", nil, err)
+		}
+	})
+}
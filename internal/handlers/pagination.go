@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// pageLinks describes the page the client just received, used to build RFC 8288 `Link` header.
+type pageLinks struct {
+	Page       int
+	PageSize   int
+	HasNext    bool
+	Total      *int64
+	CursorMode bool
+	NextCursor string
+}
+
+// buildLinkHeader returns the value of `Link` header with first/prev/next/last relations.
+// In cursor mode there is no way to jump around, so only `next` is provided.
+func buildLinkHeader(requestURL *url.URL, links pageLinks) string {
+	var relations []string
+	addRelation := func(rel string, change func(query url.Values)) {
+		query := requestURL.Query()
+		query.Del("cursor")
+		query.Set("page_size", strconv.Itoa(links.PageSize))
+		change(query)
+
+		target := url.URL{Path: requestURL.Path, RawQuery: query.Encode()}
+		relations = append(relations, fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel))
+	}
+
+	if links.CursorMode {
+		if links.HasNext {
+			addRelation("next", func(query url.Values) {
+				query.Del("page")
+				query.Set("cursor", links.NextCursor)
+			})
+		}
+		return strings.Join(relations, ", ")
+	}
+
+	setPage := func(page int) func(query url.Values) {
+		return func(query url.Values) {
+			query.Set("page", strconv.Itoa(page))
+		}
+	}
+
+	addRelation("first", setPage(1))
+	if links.Page > 1 {
+		addRelation("prev", setPage(links.Page-1))
+	}
+	if links.HasNext {
+		addRelation("next", setPage(links.Page+1))
+	}
+	if links.Total != nil {
+		addRelation("last", setPage(lastPage(*links.Total, links.PageSize)))
+	}
+	return strings.Join(relations, ", ")
+}
+
+func lastPage(total int64, pageSize int) int {
+	if total <= 0 {
+		return 1
+	}
+	return int((total + int64(pageSize) - 1) / int64(pageSize))
+}
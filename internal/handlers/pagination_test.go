@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBuildLinkHeader(t *testing.T) {
+	requestURL, _ := url.Parse("/api/ads?page=2&page_size=10&sort_by=price")
+	total := int64(35)
+
+	tests := map[string]struct {
+		links pageLinks
+		want  string
+	}{
+		"middle_page_with_total": {
+			links: pageLinks{Page: 2, PageSize: 10, HasNext: true, Total: &total},
+			want: `</api/ads?page=1&page_size=10&sort_by=price>; rel="first", ` +
+				`</api/ads?page=1&page_size=10&sort_by=price>; rel="prev", ` +
+				`</api/ads?page=3&page_size=10&sort_by=price>; rel="next", ` +
+				`</api/ads?page=4&page_size=10&sort_by=price>; rel="last"`,
+		},
+		"last_page_without_total": {
+			links: pageLinks{Page: 2, PageSize: 10, HasNext: false},
+			want: `</api/ads?page=1&page_size=10&sort_by=price>; rel="first", ` +
+				`</api/ads?page=1&page_size=10&sort_by=price>; rel="prev"`,
+		},
+		"cursor_mode": {
+			links: pageLinks{PageSize: 10, HasNext: true, CursorMode: true, NextCursor: "abc.def"},
+			want:  `</api/ads?cursor=abc.def&page_size=10&sort_by=price>; rel="next"`,
+		},
+		"cursor_mode_end_of_feed": {
+			links: pageLinks{PageSize: 10, HasNext: false, CursorMode: true},
+			want:  ``,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := buildLinkHeader(requestURL, tc.links)
+			if got != tc.want {
+				t.Fatalf("%s: expected: %s, got: %s", name, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+)
+
+func TestProfileField(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    sql.NullString
+		wantErr error
+	}{
+		"valid_value":         {value: "Москва", want: sql.NullString{String: "Москва", Valid: true}},
+		"value_trimmed":       {value: "  Москва \n", want: sql.NullString{String: "Москва", Valid: true}},
+		"empty_value_clears":  {value: "", want: sql.NullString{}},
+		"spaces_value_clears": {value: "   ", want: sql.NullString{}},
+		"cyrillic_max_length": {value: strings.Repeat("я", 10), want: sql.NullString{String: strings.Repeat("я", 10), Valid: true}},
+		"value_too_long":      {value: strings.Repeat("a", 11), wantErr: ErrInvalidLengthCity},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := profileField(tc.value, 10, ErrInvalidLengthCity)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateContactPreferences(t *testing.T) {
+	tests := map[string]struct {
+		preferences dto.ContactPreferences
+		wantErr     error
+	}{
+		"empty":                      {preferences: dto.ContactPreferences{}, wantErr: nil},
+		"chat_without_contacts":      {preferences: dto.ContactPreferences{Preferred: "chat", Public: true}, wantErr: nil},
+		"preferred_phone":            {preferences: dto.ContactPreferences{Phone: "+7 900 000-00-00", Preferred: "phone"}, wantErr: nil},
+		"preferred_email":            {preferences: dto.ContactPreferences{Email: "seller@example.com", Preferred: "email"}, wantErr: nil},
+		"preferred_telegram":         {preferences: dto.ContactPreferences{Telegram: "@seller", Preferred: "telegram"}, wantErr: nil},
+		"preferred_phone_missing":    {preferences: dto.ContactPreferences{Email: "seller@example.com", Preferred: "phone"}, wantErr: ErrMissingPreferredContactInfo},
+		"preferred_telegram_spaces":  {preferences: dto.ContactPreferences{Telegram: "   ", Preferred: "telegram"}, wantErr: ErrMissingPreferredContactInfo},
+		"unknown_preferred":          {preferences: dto.ContactPreferences{Preferred: "pigeon"}, wantErr: ErrInvalidPreferredContact},
+		"invalid_email":              {preferences: dto.ContactPreferences{Email: "not an email"}, wantErr: ErrInvalidContactEmail},
+		"email_with_display_name":    {preferences: dto.ContactPreferences{Email: "Seller <seller@example.com>"}, wantErr: ErrInvalidContactEmail},
+		"contact_too_long":           {preferences: dto.ContactPreferences{Phone: strings.Repeat("1", 101)}, wantErr: ErrInvalidLengthContact},
+		"contact_trimmed_to_max_len": {preferences: dto.ContactPreferences{Telegram: " " + strings.Repeat("a", 100) + " "}, wantErr: nil},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateContactPreferences(&tc.preferences)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+		})
+	}
+}
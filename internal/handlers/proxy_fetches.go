@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ProxyFetches lets concurrent requests for the same remote image share a single download
+// and remembers failed downloads for a while, so a slow or dead host isn't asked again
+// on every request. It's safe for concurrent use.
+type ProxyFetches struct {
+	group      singleflight.Group
+	failureTTL time.Duration
+
+	mu       sync.Mutex
+	failures map[string]proxyFailure
+}
+
+type proxyFailure struct {
+	err   error
+	until time.Time
+}
+
+func NewProxyFetches(failureTTL time.Duration) *ProxyFetches {
+	return &ProxyFetches{
+		failureTTL: failureTTL,
+		failures:   make(map[string]proxyFailure),
+	}
+}
+
+// Do runs download once for all concurrent callers with the same key. If the previous
+// download of the key has failed less than failureTTL ago, its error is returned right away.
+func (p *ProxyFetches) Do(key string, download func() error) error {
+	if err := p.failure(key); err != nil {
+		return err
+	}
+	_, err, _ := p.group.Do(key, func() (any, error) {
+		err := download()
+		if err != nil {
+			p.remember(key, err)
+		}
+		return nil, err
+	})
+	return err
+}
+
+func (p *ProxyFetches) failure(key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	failure, ok := p.failures[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(failure.until) {
+		delete(p.failures, key)
+		return nil
+	}
+	return failure.err
+}
+
+func (p *ProxyFetches) remember(key string, err error) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// expired failures are dropped here, otherwise keys that are never requested again would pile up
+	for failedKey, failure := range p.failures {
+		if now.After(failure.until) {
+			delete(p.failures, failedKey)
+		}
+	}
+	p.failures[key] = proxyFailure{err: err, until: now.Add(p.failureTTL)}
+}
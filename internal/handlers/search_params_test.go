@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/dto"
+)
+
+func TestNormalizeAdsPageSearch(t *testing.T) {
+	tests := map[string]struct {
+		input      dto.GetAdsQueryParamsRequest
+		wantQ      string
+		wantSortBy string
+		wantOrder  string
+	}{
+		"query_defaults_to_relevance": {
+			input:      dto.GetAdsQueryParamsRequest{Q: "iphone"},
+			wantQ:      "iphone",
+			wantSortBy: "relevance",
+			wantOrder:  "desc",
+		},
+		"query_is_trimmed": {
+			input:      dto.GetAdsQueryParamsRequest{Q: "  iphone 13 \t"},
+			wantQ:      "iphone 13",
+			wantSortBy: "relevance",
+			wantOrder:  "desc",
+		},
+		"explicit_sort_with_query": {
+			input:      dto.GetAdsQueryParamsRequest{Q: "iphone", SortBy: "price", Order: "asc"},
+			wantQ:      "iphone",
+			wantSortBy: "price",
+			wantOrder:  "asc",
+		},
+		"relevance_order_asc": {
+			input:      dto.GetAdsQueryParamsRequest{Q: "iphone", SortBy: "relevance", Order: "asc"},
+			wantQ:      "iphone",
+			wantSortBy: "relevance",
+			wantOrder:  "asc",
+		},
+		"empty_query": {
+			input:      dto.GetAdsQueryParamsRequest{},
+			wantQ:      "",
+			wantSortBy: "created_at",
+			wantOrder:  "desc",
+		},
+		"blank_query_is_empty": {
+			input:      dto.GetAdsQueryParamsRequest{Q: "   "},
+			wantQ:      "",
+			wantSortBy: "created_at",
+			wantOrder:  "desc",
+		},
+		"relevance_without_query": {
+			input:      dto.GetAdsQueryParamsRequest{SortBy: "relevance"},
+			wantQ:      "",
+			wantSortBy: "created_at",
+			wantOrder:  "desc",
+		},
+		"unknown_sort_with_query": {
+			input:      dto.GetAdsQueryParamsRequest{Q: "iphone", SortBy: "rank", Order: "up"},
+			wantQ:      "iphone",
+			wantSortBy: "created_at",
+			wantOrder:  "desc",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			query := tc.input
+			normalizeAdsPage(&query)
+			if query.Q != tc.wantQ {
+				t.Fatalf("%s: expected q: %q, got: %q", name, tc.wantQ, query.Q)
+			}
+			if query.SortBy != tc.wantSortBy {
+				t.Fatalf("%s: expected sort_by: %v, got: %v", name, tc.wantSortBy, query.SortBy)
+			}
+			if query.Order != tc.wantOrder {
+				t.Fatalf("%s: expected order: %v, got: %v", name, tc.wantOrder, query.Order)
+			}
+		})
+	}
+}
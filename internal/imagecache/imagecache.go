@@ -0,0 +1,252 @@
+// Package imagecache keeps copies of remote images on local disk,
+// evicting the least recently used ones when the cache grows over its size limit.
+package imagecache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrTooBig = errors.New("object doesn't fit into the cache")
+
+// Entry describes a cached object, it's stored next to the data as JSON.
+type Entry struct {
+	Key         string    `json:"key"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	ETag        string    `json:"etag"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Cache is safe for concurrent use. Objects are identified by arbitrary string keys,
+// e.g. the URL they were downloaded from.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // front is the most recently used entry
+	entries map[string]*list.Element
+}
+
+const metaSuffix = ".json"
+
+// New opens the cache in dir, objects left there by a previous run are picked up.
+func New(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Get opens the cached object. The caller must close the file.
+func (c *Cache) Get(key string) (*os.File, Entry, bool) {
+	name := fileName(key)
+
+	// the file is opened under the lock: Put replaces it under the lock as well,
+	// so the entry always describes the data in the opened file
+	c.mu.Lock()
+	element, ok := c.entries[name]
+	if !ok {
+		c.mu.Unlock()
+		return nil, Entry{}, false
+	}
+	file, err := os.Open(filepath.Join(c.dir, name))
+	if err != nil {
+		// removed behind our back, forget about it
+		c.remove(name)
+		c.mu.Unlock()
+		return nil, Entry{}, false
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(Entry)
+	c.mu.Unlock()
+
+	// modification time of metadata keeps the usage order across restarts
+	now := time.Now()
+	os.Chtimes(filepath.Join(c.dir, name+metaSuffix), now, now)
+	return file, entry, true
+}
+
+// Put stores the object read from r, replacing the previous one with the same key.
+func (c *Cache) Put(key, contentType string, r io.Reader) (Entry, error) {
+	name := fileName(key)
+
+	tmpFile, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return Entry{}, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, hash), io.LimitReader(r, c.maxSize+1))
+	if err != nil {
+		tmpFile.Close()
+		return Entry{}, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return Entry{}, err
+	}
+	if size > c.maxSize {
+		return Entry{}, ErrTooBig
+	}
+
+	entry := Entry{
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+		ETag:        `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`,
+		FetchedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmpFile.Name(), filepath.Join(c.dir, name)); err != nil {
+		return Entry{}, err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, name+metaSuffix), meta, 0o644); err != nil {
+		os.Remove(filepath.Join(c.dir, name))
+		return Entry{}, err
+	}
+
+	if element, ok := c.entries[name]; ok {
+		c.size -= element.Value.(Entry).Size
+		c.order.Remove(element)
+	}
+	c.entries[name] = c.order.PushFront(entry)
+	c.size += entry.Size
+	c.evict()
+	return entry, nil
+}
+
+// Size returns the total size of cached objects.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// evict drops least recently used objects until the cache fits into its limit, c.mu must be held.
+// Files that are being served stay readable until closed, removing them is safe.
+func (c *Cache) evict() {
+	for c.size > c.maxSize {
+		element := c.order.Back()
+		entry := c.order.Remove(element).(Entry)
+		name := fileName(entry.Key)
+		delete(c.entries, name)
+		c.size -= entry.Size
+		os.Remove(filepath.Join(c.dir, name))
+		os.Remove(filepath.Join(c.dir, name+metaSuffix))
+	}
+}
+
+// remove forgets the object, c.mu must be held.
+func (c *Cache) remove(name string) {
+	if element, ok := c.entries[name]; ok {
+		c.size -= element.Value.(Entry).Size
+		c.order.Remove(element)
+		delete(c.entries, name)
+	}
+	os.Remove(filepath.Join(c.dir, name+metaSuffix))
+}
+
+// load restores the index from metadata files, the most recently used first.
+// Broken entries and leftovers of interrupted writes are removed.
+func (c *Cache) load() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type loadedEntry struct {
+		entry    Entry
+		lastUsed time.Time
+	}
+	var loaded []loadedEntry
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !strings.HasSuffix(name, metaSuffix) {
+			continue
+		}
+
+		entry, lastUsed, err := c.readMeta(name)
+		if err != nil {
+			os.Remove(filepath.Join(c.dir, name))
+			continue
+		}
+		loaded = append(loaded, loadedEntry{entry: entry, lastUsed: lastUsed})
+	}
+
+	slices.SortFunc(loaded, func(a, b loadedEntry) int {
+		return b.lastUsed.Compare(a.lastUsed)
+	})
+	for _, item := range loaded {
+		c.entries[fileName(item.entry.Key)] = c.order.PushBack(item.entry)
+		c.size += item.entry.Size
+	}
+
+	// data without metadata can't be served
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if _, ok := c.entries[name]; !ok && !strings.HasSuffix(name, metaSuffix) {
+			os.Remove(filepath.Join(c.dir, name))
+		}
+	}
+	return nil
+}
+
+func (c *Cache) readMeta(name string) (Entry, time.Time, error) {
+	info, err := os.Stat(filepath.Join(c.dir, name))
+	if err != nil {
+		return Entry{}, time.Time{}, err
+	}
+	meta, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		return Entry{}, time.Time{}, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(meta, &entry); err != nil {
+		return Entry{}, time.Time{}, err
+	}
+	dataInfo, err := os.Stat(filepath.Join(c.dir, fileName(entry.Key)))
+	if err != nil {
+		return Entry{}, time.Time{}, err
+	}
+	if dataInfo.Size() != entry.Size {
+		return Entry{}, time.Time{}, errors.New("size of cached object doesn't match its metadata")
+	}
+	return entry, info.ModTime(), nil
+}
+
+// fileName turns any key into a safe file name.
+func fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,171 @@
+package imagecache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCachePutGet(t *testing.T) {
+	cache, err := New(t.TempDir(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putEntry, err := cache.Put("https://example.com/a.png", "image/png", strings.NewReader("first image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, entry, ok := cache.Get("https://example.com/a.png")
+	if !ok {
+		t.Fatal("expected cached object")
+	}
+	defer file.Close()
+
+	content, _ := io.ReadAll(file)
+	if string(content) != "first image" {
+		t.Fatalf("expected content: %q, got: %q", "first image", content)
+	}
+	if entry.ContentType != "image/png" || entry.ETag != putEntry.ETag || entry.ETag == "" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	if _, _, ok := cache.Get("https://example.com/missing.png"); ok {
+		t.Fatal("expected cache miss")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := New(t.TempDir(), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(key string) {
+		t.Helper()
+		if _, err := cache.Put(key, "image/png", strings.NewReader(strings.Repeat("x", 10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a")
+	put("b")
+	put("c")
+	// "a" becomes the most recently used, so "b" goes first
+	if file, _, ok := cache.Get("a"); ok {
+		file.Close()
+	}
+	put("d")
+
+	tests := map[string]struct {
+		key  string
+		want bool
+	}{
+		"recently_read":    {key: "a", want: true},
+		"least_recent":     {key: "b", want: false},
+		"older_write":      {key: "c", want: true},
+		"just_written":     {key: "d", want: true},
+		"never_been_there": {key: "e", want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			file, _, ok := cache.Get(tc.key)
+			if ok != tc.want {
+				t.Fatalf("%s: expected cached: %v, got: %v", name, tc.want, ok)
+			}
+			if ok {
+				file.Close()
+			}
+		})
+	}
+	if cache.Size() != 30 {
+		t.Fatalf("expected size: 30, got: %d", cache.Size())
+	}
+}
+
+func TestCacheTooBig(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := New(dir, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.Put("big", "image/png", strings.NewReader("too big to fit")); !errors.Is(err, ErrTooBig) {
+		t.Fatalf("expected: %v, got: %v", ErrTooBig, err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 0 {
+		t.Fatalf("expected no files left, got: %d", len(files))
+	}
+}
+
+func TestCacheReload(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := cache.Put("kept", "image/jpeg", strings.NewReader("kept image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// garbage that isn't described by any metadata
+	os.WriteFile(filepath.Join(dir, "orphan"), []byte("orphan"), 0o644)
+	os.WriteFile(filepath.Join(dir, "broken"+metaSuffix), []byte("{"), 0o644)
+
+	reopened, err := New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, reloadedEntry, ok := reopened.Get("kept")
+	if !ok {
+		t.Fatal("expected cached object after reload")
+	}
+	file.Close()
+	if reloadedEntry.ETag != entry.ETag || reopened.Size() != entry.Size {
+		t.Fatalf("expected entry: %+v, got: %+v", entry, reloadedEntry)
+	}
+
+	for _, name := range []string{"orphan", "broken" + metaSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected %s to be removed", name)
+		}
+	}
+}
+
+func TestCacheGetDuringPut(t *testing.T) {
+	cache, err := New(t.TempDir(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "https://example.com/a.png"
+	contents := []string{"first image", "second, longer image"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 200 {
+			cache.Put(key, "image/png", strings.NewReader(contents[i%2]))
+		}
+	}()
+
+	// the entry must always describe the bytes of the file it came with
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		file, entry, ok := cache.Get(key)
+		if !ok {
+			continue
+		}
+		content, _ := io.ReadAll(file)
+		file.Close()
+		if int64(len(content)) != entry.Size {
+			t.Fatalf("expected %d bytes, got: %q", entry.Size, content)
+		}
+	}
+}
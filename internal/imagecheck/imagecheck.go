@@ -0,0 +1,109 @@
+// Package imagecheck recognizes images by their content rather than by what the sender claims.
+package imagecheck
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+	"strings"
+
+	// decoders register themselves for image.DecodeConfig
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrInvalidFormat    = errors.New("invalid image format")
+	ErrFormatNotAllowed = errors.New("image format is not allowed")
+	ErrDimensionsTooBig = errors.New("image dimensions are too big")
+)
+
+// contentTypes maps names of formats supported by the service to their content types.
+var contentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// DefaultFormats are the formats accepted when nothing else is configured.
+var DefaultFormats = []string{"jpeg", "png"}
+
+// Policy describes which images the service accepts.
+type Policy struct {
+	AllowedFormats []string
+	MaxWidth       int
+	MaxHeight      int
+}
+
+// Info is what's known about the image from its header.
+type Info struct {
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Check decodes the image header from r and makes sure the image satisfies the policy.
+// Only the header is read, so r may hold just a prefix of the file.
+func (p Policy) Check(r io.Reader) (Info, error) {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return Info{}, ErrInvalidFormat
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return Info{}, ErrInvalidFormat
+	}
+	if !slices.Contains(p.AllowedFormats, format) {
+		return Info{}, ErrFormatNotAllowed
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return Info{}, ErrInvalidFormat
+	}
+	if config.Width > p.MaxWidth || config.Height > p.MaxHeight {
+		return Info{}, ErrDimensionsTooBig
+	}
+
+	return Info{Format: format, ContentType: contentType, Width: config.Width, Height: config.Height}, nil
+}
+
+// ParseFormats parses comma separated list of formats like "jpeg,png,webp".
+func ParseFormats(value string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(value, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		if format == "jpg" {
+			format = "jpeg"
+		}
+		if _, ok := contentTypes[format]; !ok {
+			return nil, fmt.Errorf("unsupported image format %q", format)
+		}
+		formats = append(formats, format)
+	}
+	if len(formats) == 0 {
+		return nil, errors.New("at least one image format must be allowed")
+	}
+	return formats, nil
+}
+
+// ContentTypeMatches reports whether the declared content type agrees with the detected format.
+// Servers that don't know what they serve (no type or a generic binary one) are given the benefit of the doubt.
+func ContentTypeMatches(declared string, info Info) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(declared, ";")[0]))
+	switch mediaType {
+	case "", "application/octet-stream", "binary/octet-stream":
+		return true
+	case "image/jpg", "image/pjpeg":
+		mediaType = "image/jpeg"
+	}
+	return mediaType == info.ContentType
+}
@@ -0,0 +1,137 @@
+package imagecheck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	policy := Policy{AllowedFormats: []string{"jpeg", "png", "webp"}, MaxWidth: 100, MaxHeight: 50}
+
+	tests := map[string]struct {
+		data     []byte
+		wantErr  error
+		wantType string
+	}{
+		"png":            {data: encodePNG(t, 100, 50), wantType: "image/png"},
+		"jpeg":           {data: encodeJPEG(t, 20, 10), wantType: "image/jpeg"},
+		"webp":           {data: webpHeader(64, 32), wantType: "image/webp"},
+		"header_only":    {data: encodePNG(t, 10, 10)[:33], wantType: "image/png"},
+		"too_wide":       {data: encodePNG(t, 101, 10), wantErr: ErrDimensionsTooBig},
+		"too_high":       {data: webpHeader(10, 51), wantErr: ErrDimensionsTooBig},
+		"not_allowed":    {data: encodeGIF(t), wantErr: ErrFormatNotAllowed},
+		"truncated":      {data: encodePNG(t, 10, 10)[:20], wantErr: ErrInvalidFormat},
+		"not_an_image":   {data: []byte("<html></html>"), wantErr: ErrInvalidFormat},
+		"empty_response": {data: nil, wantErr: ErrInvalidFormat},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			info, err := policy.Check(bytes.NewReader(tc.data))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+			if info.ContentType != tc.wantType {
+				t.Fatalf("%s: expected content type: %q, got: %q", name, tc.wantType, info.ContentType)
+			}
+		})
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		"single":       {value: "png", want: []string{"png"}},
+		"with_spaces":  {value: " JPG, webp ,gif", want: []string{"jpeg", "webp", "gif"}},
+		"unknown":      {value: "png,bmp", wantErr: true},
+		"only_commas":  {value: ",,", wantErr: true},
+		"empty_string": {value: "", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseFormats(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("%s: expected error: %v, got: %v", name, tc.wantErr, err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestContentTypeMatches(t *testing.T) {
+	jpegInfo := Info{Format: "jpeg", ContentType: "image/jpeg"}
+
+	tests := map[string]struct {
+		declared string
+		want     bool
+	}{
+		"same":         {declared: "image/jpeg", want: true},
+		"alias":        {declared: "image/jpg", want: true},
+		"parameters":   {declared: "Image/JPEG; charset=binary", want: true},
+		"unknown_type": {declared: "application/octet-stream", want: true},
+		"missing":      {declared: "", want: true},
+		"other_image":  {declared: "image/png", want: false},
+		"html":         {declared: "text/html", want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ContentTypeMatches(tc.declared, jpegInfo); got != tc.want {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewGray(image.Rect(0, 0, 10, 10)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// webpHeader builds the header of a lossless webp image, which is enough for DecodeConfig.
+func webpHeader(width, height int) []byte {
+	bits := uint32(width-1) | uint32(height-1)<<14
+	chunk := []byte{0x2f, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(chunk[1:], bits)
+
+	data := []byte("RIFF")
+	data = binary.LittleEndian.AppendUint32(data, uint32(4+8+len(chunk)))
+	data = append(data, "WEBPVP8L"...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(chunk)))
+	return append(data, chunk...)
+}
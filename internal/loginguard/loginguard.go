@@ -0,0 +1,235 @@
+// Package loginguard slows down password guessing.
+//
+// Failed logins are counted per login and per client IP. After a number of free
+// attempts every further failure locks the key for twice as long as the previous
+// one, up to a maximum. Counters start over after a quiet period or a successful login.
+//
+// An attempt is counted as a failure before the password is checked and taken back
+// once it turns out right, so parallel requests can't all slip through a lock that
+// only the first of them would have triggered.
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrTooManyAttempts = errors.New("too many login attempts, try again later")
+
+// Entry is the failure counter of a key.
+type Entry struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store keeps failure counters. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the counter of the key, the zero Entry if there were no failures.
+	Get(ctx context.Context, key string) (Entry, error)
+	// Fail counts a failure at now. A counter whose last failure is before resetBefore starts over.
+	// The increment must be atomic, the returned Entry is the counter right after it.
+	Fail(ctx context.Context, key string, now, resetBefore time.Time) (Entry, error)
+	// Release takes back a failure counted at failedAt. If it's still the last failure,
+	// previousFailure becomes the last one again.
+	Release(ctx context.Context, key string, failedAt, previousFailure time.Time) error
+	// Reset forgets the failures of the key.
+	Reset(ctx context.Context, key string) error
+	// Cleanup may drop counters whose last failure is before the given time.
+	Cleanup(ctx context.Context, before time.Time) error
+}
+
+// Policy sets how fast a key gets locked.
+type Policy struct {
+	// FreeAttempts failures are allowed without any delay.
+	FreeAttempts int
+	// BaseDelay is the lock after the first failure beyond the free attempts.
+	BaseDelay time.Duration
+	// MaxDelay caps the lock.
+	MaxDelay time.Duration
+	// ResetAfter without failures the counter starts over.
+	ResetAfter time.Duration
+}
+
+// Delay returns how long a key with the given number of failures stays locked after the last one.
+func (p Policy) Delay(failures int) time.Duration {
+	excess := failures - p.FreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < excess && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Guard checks and counts login attempts.
+type Guard struct {
+	store       Store
+	prefix      string
+	loginPolicy Policy
+	ipPolicy    Policy
+	now         func() time.Time
+}
+
+// New creates a guard applying loginPolicy to logins and ipPolicy to client IPs.
+func New(store Store, loginPolicy, ipPolicy Policy) *Guard {
+	return NewWithPrefix("", store, loginPolicy, ipPolicy)
+}
+
+// NewWithPrefix creates a guard whose keys start with prefix, so guards throttling different
+// requests can share a store. Their ResetAfter should match: cleanup covers the whole store.
+func NewWithPrefix(prefix string, store Store, loginPolicy, ipPolicy Policy) *Guard {
+	return &Guard{
+		store:       store,
+		prefix:      prefix,
+		loginPolicy: loginPolicy,
+		ipPolicy:    ipPolicy,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Attempt is a login attempt counted in advance by Reserve.
+type Attempt struct {
+	login     string
+	ip        string
+	at        time.Time
+	lastLogin time.Time
+	lastIP    time.Time
+}
+
+// Reserve counts the attempt as a failure of both the login and the IP and returns how long
+// the client has to wait before trying again, zero if the attempt may go on. It counts unknown
+// logins too, so locks don't reveal which logins exist. A locked key isn't counted any further,
+// but attempts that lose a race with a parallel one stay counted.
+func (g *Guard) Reserve(ctx context.Context, login, ip string) (Attempt, time.Duration, error) {
+	now := g.now()
+	loginEntry, loginWait, err := g.wait(ctx, g.loginKey(login), g.loginPolicy, now)
+	if err != nil {
+		return Attempt{}, 0, err
+	}
+	ipEntry, ipWait, err := g.wait(ctx, g.ipKey(ip), g.ipPolicy, now)
+	if err != nil {
+		return Attempt{}, 0, err
+	}
+	if wait := max(loginWait, ipWait); wait > 0 {
+		return Attempt{}, wait, nil
+	}
+
+	if err := g.store.Cleanup(ctx, now.Add(-max(g.loginPolicy.ResetAfter, g.ipPolicy.ResetAfter))); err != nil {
+		return Attempt{}, 0, err
+	}
+	loginWait, err = g.reserve(ctx, g.loginKey(login), g.loginPolicy, loginEntry, now)
+	if err != nil {
+		return Attempt{}, 0, err
+	}
+	ipWait, err = g.reserve(ctx, g.ipKey(ip), g.ipPolicy, ipEntry, now)
+	if err != nil {
+		return Attempt{}, 0, err
+	}
+
+	attempt := Attempt{
+		login:     login,
+		ip:        ip,
+		at:        now,
+		lastLogin: loginEntry.LastFailure,
+		lastIP:    ipEntry.LastFailure,
+	}
+	return attempt, max(loginWait, ipWait), nil
+}
+
+// Release takes back a reserved attempt that turned out right.
+func (g *Guard) Release(ctx context.Context, attempt Attempt) error {
+	if err := g.store.Release(ctx, g.loginKey(attempt.login), attempt.at, attempt.lastLogin); err != nil {
+		return err
+	}
+	return g.store.Release(ctx, g.ipKey(attempt.ip), attempt.at, attempt.lastIP)
+}
+
+// ReserveToken counts an attempt to use a short-lived token, like the one for the second factor,
+// and reports whether it's one of the first maxAttempts. Such tokens expire long before their
+// counters would start over, so a stolen token can't be used for more guesses than that.
+func (g *Guard) ReserveToken(ctx context.Context, tokenID string, maxAttempts int) (bool, error) {
+	now := g.now()
+	entry, err := g.store.Fail(ctx, g.tokenKey(tokenID), now, now.Add(-g.loginPolicy.ResetAfter))
+	if err != nil {
+		return false, err
+	}
+	return entry.Failures <= maxAttempts, nil
+}
+
+// UseToken marks a short-lived token as used and reports whether this is its first use,
+// so it's exchanged only once, even by concurrent requests with different valid codes.
+func (g *Guard) UseToken(ctx context.Context, tokenID string) (bool, error) {
+	now := g.now()
+	entry, err := g.store.Fail(ctx, g.usedTokenKey(tokenID), now, now.Add(-g.loginPolicy.ResetAfter))
+	if err != nil {
+		return false, err
+	}
+	return entry.Failures == 1, nil
+}
+
+// Reset forgets the failures of the login once it's logged in or its password is replaced.
+// The IP counter is left alone, otherwise an attacker with one valid account could reset
+// it between guesses.
+func (g *Guard) Reset(ctx context.Context, login string) error {
+	return g.store.Reset(ctx, g.loginKey(login))
+}
+
+// Failures returns the failed attempts counted for the login, the zero Entry once they started over.
+func (g *Guard) Failures(ctx context.Context, login string) (Entry, error) {
+	entry, err := g.store.Get(ctx, g.loginKey(login))
+	if err != nil {
+		return Entry{}, err
+	}
+	if entry.LastFailure.Before(g.now().Add(-g.loginPolicy.ResetAfter)) {
+		return Entry{}, nil
+	}
+	return entry, nil
+}
+
+// wait returns the counter of the key and how long it stays locked.
+func (g *Guard) wait(ctx context.Context, key string, policy Policy, now time.Time) (Entry, time.Duration, error) {
+	entry, err := g.store.Get(ctx, key)
+	if err != nil {
+		return Entry{}, 0, err
+	}
+	if entry.Failures == 0 || entry.LastFailure.Before(now.Add(-policy.ResetAfter)) {
+		return Entry{}, 0, nil
+	}
+	lockedUntil := entry.LastFailure.Add(policy.Delay(entry.Failures))
+	if !lockedUntil.After(now) {
+		return entry, 0, nil
+	}
+	return entry, lockedUntil.Sub(now), nil
+}
+
+// reserve counts the failure in advance. The key was unlocked with the seen counter, if parallel
+// attempts were counted in between, the last of them may have locked it already.
+func (g *Guard) reserve(ctx context.Context, key string, policy Policy, seen Entry, now time.Time) (time.Duration, error) {
+	entry, err := g.store.Fail(ctx, key, now, now.Add(-policy.ResetAfter))
+	if err != nil {
+		return 0, err
+	}
+	if entry.Failures-1 <= seen.Failures {
+		return 0, nil
+	}
+	return policy.Delay(entry.Failures - 1), nil
+}
+
+func (g *Guard) loginKey(login string) string {
+	return g.prefix + "login:" + login
+}
+
+func (g *Guard) ipKey(ip string) string {
+	return g.prefix + "ip:" + ip
+}
+
+func (g *Guard) tokenKey(tokenID string) string {
+	return g.prefix + "token:" + tokenID
+}
+
+func (g *Guard) usedTokenKey(tokenID string) string {
+	return g.prefix + "used:" + tokenID
+}
@@ -0,0 +1,228 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := map[string]struct {
+		failures int
+		want     time.Duration
+	}{
+		"no_failures":       {failures: 0, want: 0},
+		"free_attempts":     {failures: 3, want: 0},
+		"first_locked":      {failures: 4, want: time.Second},
+		"doubled":           {failures: 6, want: 4 * time.Second},
+		"capped":            {failures: 8, want: 10 * time.Second},
+		"far_beyond_capped": {failures: 1000, want: 10 * time.Second},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := policy.Delay(tc.failures); got != tc.want {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard := New(
+		NewMemoryStore(),
+		Policy{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: time.Minute, ResetAfter: time.Hour},
+		Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute, ResetAfter: time.Hour},
+	)
+	guard.now = func() time.Time { return now }
+
+	reserve := func(login, ip string, want time.Duration) Attempt {
+		t.Helper()
+		attempt, got, err := guard.Reserve(ctx, login, ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("%s from %s: expected: %v, got: %v", login, ip, want, got)
+		}
+		return attempt
+	}
+	fail := func(login, ip string) {
+		t.Helper()
+		reserve(login, ip, 0)
+	}
+	succeed := func(login, ip string) {
+		t.Helper()
+		if err := guard.Release(ctx, reserve(login, ip, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the login gets locked after its free attempts, other logins from another IP aren't affected
+	for range 3 {
+		fail("victim", "10.0.0.1")
+	}
+	reserve("victim", "10.0.0.2", time.Second)
+	succeed("other", "10.0.0.2")
+	now = now.Add(time.Second)
+	fail("victim", "10.0.0.1")
+	reserve("victim", "10.0.0.2", 2*time.Second)
+
+	// the IP gets locked after its free attempts, whatever login it tries
+	reserve("other", "10.0.0.1", time.Second)
+
+	// the lock runs out, a right password doesn't renew it
+	now = now.Add(2 * time.Second)
+	succeed("victim", "10.0.0.2")
+	succeed("victim", "10.0.0.2")
+
+	// reset clears the login, but not the IP
+	if err := guard.Reset(ctx, "victim"); err != nil {
+		t.Fatal(err)
+	}
+	fail("victim", "10.0.0.1")
+	succeed("victim", "10.0.0.2")
+	reserve("other", "10.0.0.1", 2*time.Second)
+
+	// counters start over after a quiet period
+	if entry, err := guard.Failures(ctx, "victim"); err != nil || entry.Failures != 1 {
+		t.Fatalf("expected: %v, got: %v, %v", 1, entry.Failures, err)
+	}
+	now = now.Add(2 * time.Hour)
+	if entry, err := guard.Failures(ctx, "victim"); err != nil || entry.Failures != 0 {
+		t.Fatalf("expected: %v, got: %v, %v", 0, entry.Failures, err)
+	}
+	fail("other", "10.0.0.1")
+	succeed("other", "10.0.0.1")
+}
+
+func TestGuardParallelAttempts(t *testing.T) {
+	ctx := context.Background()
+	loginPolicy := Policy{FreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, ResetAfter: time.Hour}
+	guard := New(
+		NewMemoryStore(),
+		loginPolicy,
+		Policy{FreeAttempts: 1000, BaseDelay: time.Minute, MaxDelay: time.Hour, ResetAfter: time.Hour},
+	)
+
+	// all the guesses are sent at once, none of them has failed yet when the others are checked
+	const attempts = 50
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, retryAfter, err := guard.Reserve(ctx, "victim", "10.0.0.1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if retryAfter == 0 {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// one at a time the attempt after the free ones is still allowed, it's the one that locks the login
+	if want := int32(loginPolicy.FreeAttempts + 1); allowed.Load() != want {
+		t.Fatalf("expected: %v, got: %v", want, allowed.Load())
+	}
+}
+
+func TestGuardReserveToken(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute, ResetAfter: time.Hour}
+	guard := New(NewMemoryStore(), policy, policy)
+
+	// parallel attempts with one token get no more than the limit
+	const attempts = 20
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := guard.ReserveToken(ctx, "token-1", 5)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed.Load() != 5 {
+		t.Fatalf("expected: %v, got: %v", 5, allowed.Load())
+	}
+
+	// other tokens have their own limit
+	ok, err := guard.ReserveToken(ctx, "token-2", 5)
+	if err != nil || !ok {
+		t.Fatalf("expected: %v, got: %v, %v", true, ok, err)
+	}
+}
+
+func TestGuardUseToken(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute, ResetAfter: time.Hour}
+	guard := New(NewMemoryStore(), policy, policy)
+
+	// parallel exchanges of one token, only one of them wins
+	const attempts = 20
+	var wg sync.WaitGroup
+	var used atomic.Int32
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := guard.UseToken(ctx, "token-1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				used.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if used.Load() != 1 {
+		t.Fatalf("expected: %v, got: %v", 1, used.Load())
+	}
+
+	// using a token doesn't count as an attempt to guess its code
+	ok, err := guard.ReserveToken(ctx, "token-1", 1)
+	if err != nil || !ok {
+		t.Fatalf("expected: %v, got: %v, %v", true, ok, err)
+	}
+}
+
+func TestGuardPrefix(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	policy := Policy{FreeAttempts: 0, BaseDelay: time.Minute, MaxDelay: time.Hour, ResetAfter: time.Hour}
+	loginGuard := New(store, policy, policy)
+	resetGuard := NewWithPrefix("reset:", store, policy, policy)
+
+	if _, retryAfter, err := resetGuard.Reserve(ctx, "victim", "10.0.0.1"); err != nil || retryAfter != 0 {
+		t.Fatalf("expected: %v, got: %v, %v", 0, retryAfter, err)
+	}
+	if _, retryAfter, err := resetGuard.Reserve(ctx, "victim", "10.0.0.1"); err != nil || retryAfter == 0 {
+		t.Fatalf("expected a lock, got: %v, %v", retryAfter, err)
+	}
+
+	// the guards share the store, but not the counters
+	if _, retryAfter, err := loginGuard.Reserve(ctx, "victim", "10.0.0.1"); err != nil || retryAfter != 0 {
+		t.Fatalf("expected: %v, got: %v, %v", 0, retryAfter, err)
+	}
+}
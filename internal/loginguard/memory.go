@@ -0,0 +1,81 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps counters in memory, each instance of the service counts on its own.
+type MemoryStore struct {
+	mu          sync.Mutex
+	entries     map[string]Entry
+	lastCleanup time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:     make(map[string]Entry),
+		lastCleanup: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, now, resetBefore time.Time) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[key]
+	if entry.LastFailure.Before(resetBefore) {
+		entry.Failures = 0
+	}
+	entry.Failures++
+	entry.LastFailure = now
+	s.entries[key] = entry
+	return entry, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string, failedAt, previousFailure time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	entry.Failures = max(entry.Failures-1, 0)
+	if entry.LastFailure.Equal(failedAt) {
+		entry.LastFailure = previousFailure
+	}
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Cleanup scans the counters at most once a minute.
+func (s *MemoryStore) Cleanup(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastCleanup) < time.Minute {
+		return nil
+	}
+	for key, entry := range s.entries {
+		if entry.LastFailure.Before(before) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastCleanup = time.Now()
+	return nil
+}
@@ -0,0 +1,73 @@
+package loginguard
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+)
+
+// Queries is the part of database.Queries PostgresStore relies on.
+type Queries interface {
+	GetLoginFailure(ctx context.Context, key string) (database.LoginFailure, error)
+	RecordLoginFailure(ctx context.Context, arg database.RecordLoginFailureParams) (database.LoginFailure, error)
+	ReleaseLoginFailure(ctx context.Context, arg database.ReleaseLoginFailureParams) error
+	DeleteLoginFailure(ctx context.Context, key string) error
+	DeleteStaleLoginFailures(ctx context.Context, lastFailureAt time.Time) error
+}
+
+// PostgresStore keeps counters in the login_failures table, shared by all instances of the service.
+type PostgresStore struct {
+	db Queries
+}
+
+func NewPostgresStore(db Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (Entry, error) {
+	row, err := s.db.GetLoginFailure(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, nil
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Failures: int(row.Failures), LastFailure: row.LastFailureAt}, nil
+}
+
+func (s *PostgresStore) Fail(ctx context.Context, key string, now, resetBefore time.Time) (Entry, error) {
+	row, err := s.db.RecordLoginFailure(
+		ctx,
+		database.RecordLoginFailureParams{
+			Key:           key,
+			LastFailureAt: now,
+			ResetBefore:   resetBefore,
+		},
+	)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Failures: int(row.Failures), LastFailure: row.LastFailureAt}, nil
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string, failedAt, previousFailure time.Time) error {
+	return s.db.ReleaseLoginFailure(
+		ctx,
+		database.ReleaseLoginFailureParams{
+			Key:               key,
+			FailedAt:          failedAt,
+			PreviousFailureAt: previousFailure,
+		},
+	)
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.db.DeleteLoginFailure(ctx, key)
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context, before time.Time) error {
+	return s.db.DeleteStaleLoginFailures(ctx, before)
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	notifier := NewFileNotifier(path)
+
+	messages := []Message{
+		{To: "alice", Subject: "first", Body: "first body"},
+		{To: "bob", Subject: "second", Body: "second body"},
+	}
+	for _, msg := range messages {
+		if err := notifier.Send(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: alice", "Subject: first", "first body", "To: bob", "second body"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected %q in:\n%s", want, data)
+		}
+	}
+	if strings.Index(string(data), "alice") > strings.Index(string(data), "bob") {
+		t.Fatalf("messages must be appended in order:\n%s", data)
+	}
+}
@@ -0,0 +1,190 @@
+// Package revocation keeps track of access tokens revoked before their expiry.
+//
+// Revocations are stored in Postgres and cached in memory. Positive answers are cached
+// until the token expires, negative ones for a short time only, so revocations made
+// by other instances of the service take effect after at most that long.
+package revocation
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/google/uuid"
+)
+
+// Queries is the part of database.Queries the store relies on.
+type Queries interface {
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeToken(ctx context.Context, arg database.RevokeTokenParams) error
+	DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) error
+	GetUserTokensValidAfter(ctx context.Context, id uuid.UUID) (sql.NullTime, error)
+	UpdateUserTokensValidAfter(ctx context.Context, arg database.UpdateUserTokensValidAfterParams) error
+}
+
+type tokenEntry struct {
+	revoked   bool
+	expiresAt time.Time
+	checkedAt time.Time
+}
+
+type userEntry struct {
+	validAfter time.Time
+	checkedAt  time.Time
+}
+
+// Store implements auth.Revocations, it's safe for concurrent use.
+type Store struct {
+	db  Queries
+	ttl time.Duration
+
+	mu          sync.Mutex
+	tokens      map[string]tokenEntry
+	users       map[uuid.UUID]userEntry
+	lastCleanup time.Time
+}
+
+// New creates a store caching negative answers for ttl.
+func New(db Queries, ttl time.Duration) *Store {
+	return &Store{
+		db:          db,
+		ttl:         ttl,
+		tokens:      make(map[string]tokenEntry),
+		users:       make(map[uuid.UUID]userEntry),
+		lastCleanup: time.Now(),
+	}
+}
+
+// IsRevoked reports whether the token itself was revoked or its user logged out everywhere after it was issued.
+func (s *Store) IsRevoked(ctx context.Context, token auth.TokenInfo) (bool, error) {
+	validAfter, err := s.userValidAfter(ctx, token.UserID)
+	if err != nil {
+		return false, err
+	}
+	// the issue time is truncated to seconds, a token dated the second of the revocation may be older than it
+	if !validAfter.IsZero() && !token.IssuedAt.After(validAfter) {
+		return true, nil
+	}
+
+	// tokens issued before jti was introduced can only be revoked all at once
+	if token.ID == "" {
+		return false, nil
+	}
+	return s.tokenRevoked(ctx, token)
+}
+
+// Revoke rejects the token from now on.
+func (s *Store) Revoke(ctx context.Context, token auth.TokenInfo) error {
+	now := time.Now().UTC()
+	// rows of expired tokens are of no use, this keeps the table small
+	if err := s.db.DeleteExpiredRevokedTokens(ctx, now); err != nil {
+		return err
+	}
+	err := s.db.RevokeToken(
+		ctx,
+		database.RevokeTokenParams{
+			Jti:       token.ID,
+			UserID:    token.UserID,
+			ExpiresAt: token.ExpiresAt,
+			RevokedAt: now,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token.ID] = tokenEntry{revoked: true, expiresAt: token.ExpiresAt, checkedAt: now}
+	return nil
+}
+
+// RevokeAll rejects every token of the user issued up to now and returns that moment.
+// Tokens made for the user right after it have to be dated later, see auth.MakeJWTAfter.
+func (s *Store) RevokeAll(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	// the column keeps microseconds
+	validAfter := time.Now().UTC().Truncate(time.Microsecond)
+	err := s.db.UpdateUserTokensValidAfter(
+		ctx,
+		database.UpdateUserTokensValidAfterParams{
+			ID:               userID,
+			TokensValidAfter: sql.NullTime{Time: validAfter, Valid: true},
+		},
+	)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.SetValidAfter(userID, validAfter)
+	return validAfter, nil
+}
+
+// SetValidAfter caches validAfter the caller has already written to the database itself, e.g. in a transaction.
+func (s *Store) SetValidAfter(userID uuid.UUID, validAfter time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID] = userEntry{validAfter: validAfter, checkedAt: time.Now()}
+}
+
+func (s *Store) userValidAfter(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	s.mu.Lock()
+	entry, ok := s.users[userID]
+	s.mu.Unlock()
+	if ok && time.Since(entry.checkedAt) < s.ttl {
+		return entry.validAfter, nil
+	}
+
+	validAfter, err := s.db.GetUserTokensValidAfter(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID] = userEntry{validAfter: validAfter.Time, checkedAt: time.Now()}
+	s.cleanup()
+	return validAfter.Time, nil
+}
+
+func (s *Store) tokenRevoked(ctx context.Context, token auth.TokenInfo) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.tokens[token.ID]
+	s.mu.Unlock()
+	if ok && (entry.revoked || time.Since(entry.checkedAt) < s.ttl) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.db.IsTokenRevoked(ctx, token.ID)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token.ID] = tokenEntry{revoked: revoked, expiresAt: token.ExpiresAt, checkedAt: time.Now()}
+	s.cleanup()
+	return revoked, nil
+}
+
+// cleanup drops entries that can't be used anymore, s.mu must be held.
+func (s *Store) cleanup() {
+	now := time.Now()
+	if now.Sub(s.lastCleanup) < s.ttl {
+		return
+	}
+	s.lastCleanup = now
+
+	for id, entry := range s.tokens {
+		if now.After(entry.expiresAt) || (!entry.revoked && now.Sub(entry.checkedAt) >= s.ttl) {
+			delete(s.tokens, id)
+		}
+	}
+	for id, entry := range s.users {
+		if now.Sub(entry.checkedAt) >= s.ttl {
+			delete(s.users, id)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package revocation
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/englandrecoil/go-marketplace-service/internal/auth"
+	"github.com/englandrecoil/go-marketplace-service/internal/database"
+	"github.com/google/uuid"
+)
+
+type fakeQueries struct {
+	revoked    map[string]bool
+	validAfter map[uuid.UUID]time.Time
+	lookups    int
+}
+
+func newFakeQueries() *fakeQueries {
+	return &fakeQueries{revoked: make(map[string]bool), validAfter: make(map[uuid.UUID]time.Time)}
+}
+
+func (q *fakeQueries) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	q.lookups++
+	return q.revoked[jti], nil
+}
+
+func (q *fakeQueries) RevokeToken(ctx context.Context, arg database.RevokeTokenParams) error {
+	q.revoked[arg.Jti] = true
+	return nil
+}
+
+func (q *fakeQueries) DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) error {
+	return nil
+}
+
+func (q *fakeQueries) GetUserTokensValidAfter(ctx context.Context, id uuid.UUID) (sql.NullTime, error) {
+	q.lookups++
+	validAfter, ok := q.validAfter[id]
+	return sql.NullTime{Time: validAfter, Valid: ok}, nil
+}
+
+func (q *fakeQueries) UpdateUserTokensValidAfter(ctx context.Context, arg database.UpdateUserTokensValidAfterParams) error {
+	q.validAfter[arg.ID] = arg.TokensValidAfter.Time
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	queries := newFakeQueries()
+	store := New(queries, time.Minute)
+
+	now := time.Now().UTC()
+	token := func(userID uuid.UUID, issuedAt time.Time) auth.TokenInfo {
+		return auth.TokenInfo{ID: uuid.NewString(), UserID: userID, IssuedAt: issuedAt, ExpiresAt: issuedAt.Add(time.Hour)}
+	}
+	loggedOut, other := uuid.New(), uuid.New()
+	revokedToken := token(other, now)
+	oldToken := token(loggedOut, now.Add(-time.Hour))
+	if err := store.Revoke(ctx, revokedToken); err != nil {
+		t.Fatal(err)
+	}
+	validAfter, err := store.RevokeAll(ctx, loggedOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// issue times have second precision
+	logoutSecond := validAfter.Truncate(time.Second)
+
+	tests := map[string]struct {
+		token auth.TokenInfo
+		want  bool
+	}{
+		"revoked_token":          {token: revokedToken, want: true},
+		"other_token":            {token: token(other, now), want: false},
+		"before_logout":          {token: oldToken, want: true},
+		"old_without_jti":        {token: auth.TokenInfo{UserID: loggedOut, IssuedAt: oldToken.IssuedAt}, want: true},
+		"same_second_as_logout":  {token: token(loggedOut, logoutSecond), want: true},
+		"after_logout":           {token: token(loggedOut, logoutSecond.Add(time.Second)), want: false},
+		"unknown_user_valid":     {token: token(uuid.New(), now), want: false},
+		"not_revoked_without_id": {token: auth.TokenInfo{UserID: other, IssuedAt: now}, want: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := store.IsRevoked(ctx, tc.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStoreCache(t *testing.T) {
+	ctx := context.Background()
+	queries := newFakeQueries()
+	store := New(queries, time.Minute)
+
+	token := auth.TokenInfo{ID: uuid.NewString(), UserID: uuid.New(), IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	for range 3 {
+		if _, err := store.IsRevoked(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// one lookup of the user and one of the token
+	if queries.lookups != 2 {
+		t.Fatalf("expected lookups: 2, got: %d", queries.lookups)
+	}
+
+	// revoked by another instance, visible once the cached answer is stale
+	queries.revoked[token.ID] = true
+	if revoked, _ := store.IsRevoked(ctx, token); revoked {
+		t.Fatal("expected cached answer")
+	}
+	store.ttl = 0
+	if revoked, _ := store.IsRevoked(ctx, token); !revoked {
+		t.Fatal("expected revoked token after cache expiry")
+	}
+}
@@ -0,0 +1,28 @@
+-- name: CreateAdImage :one
+INSERT INTO ad_images(id, ad_id, image_address, image_id, position, is_cover, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetAdImages :many
+SELECT * FROM ad_images
+WHERE ad_id = $1
+ORDER BY position;
+
+-- name: DeleteAdImages :exec
+DELETE FROM ad_images
+WHERE ad_id = $1;
+
+-- name: UpdateAdCoverImage :exec
+UPDATE ad_images
+SET 
+  image_address = $2,
+  image_id = $3
+WHERE ad_id = $1 AND is_cover;
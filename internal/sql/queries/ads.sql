@@ -1,5 +1,5 @@
 -- name: CreateAdvertisement :one
-INSERT INTO advertisements(id, title, description, image_address, price, created_at, updated_at, user_id)
+INSERT INTO advertisements(id, title, description, image_address, price, created_at, updated_at, user_id, status, category_id, image_id)
 VALUES (
     gen_random_uuid(),
     $1,
@@ -8,27 +8,210 @@ VALUES (
     $4,
     $5,
     $6,
-    $7
+    $7,
+    $8,
+    $9,
+    $10
 ) 
 RETURNING *;
 
 -- name: GetAdvertisements :many
 SELECT 
+  ads.id, 
   ads.title, 
   ads.description, 
   ads.image_address, 
+  ads.image_id, 
   ads.price, 
+  ads.status, 
+  ads.category_id, 
+  ads.created_at, 
   ads.user_id, 
-  users.login AS author_login
+  users.login AS author_login,
+  images.variant_widths AS image_variant_widths,
+  COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', sqlc.narg(query)) || websearch_to_tsquery('english', sqlc.narg(query))), 0)::real AS search_rank
 FROM advertisements AS ads
 JOIN users ON users.id = ads.user_id
+LEFT JOIN images ON images.id = ads.image_id
 WHERE 
   (sqlc.arg(min_price)::int IS NULL OR ads.price >= sqlc.arg(min_price))
   AND (sqlc.arg(max_price)::int IS NULL OR ads.price <= sqlc.arg(max_price))
+  AND ads.status = sqlc.arg(status)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR ads.user_id = sqlc.narg(user_id))
+  -- ads with a dead image are hidden from everyone but their author
+  AND (sqlc.arg(include_broken)::boolean OR NOT ads.image_broken)
+  AND (
+    sqlc.narg(query)::text IS NULL
+    OR ads.search_vector @@ (websearch_to_tsquery('russian', sqlc.narg(query)) || websearch_to_tsquery('english', sqlc.narg(query)))
+  )
+  AND (
+    sqlc.narg(category_id)::int IS NULL
+    OR ads.category_id IN (
+      WITH RECURSIVE subcategories AS (
+        SELECT id FROM categories WHERE id = sqlc.narg(category_id)
+        UNION ALL
+        SELECT categories.id FROM categories
+        JOIN subcategories ON categories.parent_id = subcategories.id
+      )
+      SELECT id FROM subcategories
+    )
+  )
+  -- keyset pagination: continue right after the (sort key, id) pair of the last seen ad
+  AND (
+    sqlc.narg(cursor_id)::uuid IS NULL
+    OR (sqlc.arg(order_by) = 'price' AND sqlc.arg(order_dir) = 'asc'
+      AND (ads.price, ads.id) > (sqlc.narg(cursor_price)::int, sqlc.narg(cursor_id)))
+    OR (sqlc.arg(order_by) = 'price' AND sqlc.arg(order_dir) = 'desc'
+      AND (ads.price, ads.id) < (sqlc.narg(cursor_price)::int, sqlc.narg(cursor_id)))
+    OR (sqlc.arg(order_by) = 'created_at' AND sqlc.arg(order_dir) = 'asc'
+      AND (ads.created_at, ads.id) > (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)))
+    OR (sqlc.arg(order_by) = 'created_at' AND sqlc.arg(order_dir) = 'desc'
+      AND (ads.created_at, ads.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)))
+    OR (sqlc.arg(order_by) = 'relevance' AND sqlc.arg(order_dir) = 'asc'
+      AND (COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', sqlc.narg(query)) || websearch_to_tsquery('english', sqlc.narg(query))), 0)::real, ads.id) > (sqlc.narg(cursor_rank)::real, sqlc.narg(cursor_id)))
+    OR (sqlc.arg(order_by) = 'relevance' AND sqlc.arg(order_dir) = 'desc'
+      AND (COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', sqlc.narg(query)) || websearch_to_tsquery('english', sqlc.narg(query))), 0)::real, ads.id) < (sqlc.narg(cursor_rank)::real, sqlc.narg(cursor_id)))
+  )
 ORDER BY
   CASE WHEN sqlc.arg(order_by) = 'price'      AND sqlc.arg(order_dir) = 'asc'  THEN ads.price     END ASC,
   CASE WHEN sqlc.arg(order_by) = 'price'      AND sqlc.arg(order_dir) = 'desc' THEN ads.price     END DESC,
   CASE WHEN sqlc.arg(order_by) = 'created_at' AND sqlc.arg(order_dir) = 'asc'  THEN ads.created_at END ASC,
   CASE WHEN sqlc.arg(order_by) = 'created_at' AND sqlc.arg(order_dir) = 'desc' THEN ads.created_at END DESC,
-  ads.created_at DESC
-LIMIT $1 OFFSET $2;
\ No newline at end of file
+  CASE WHEN sqlc.arg(order_by) = 'relevance'  AND sqlc.arg(order_dir) = 'asc'
+    THEN COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', sqlc.narg(query)) || websearch_to_tsquery('english', sqlc.narg(query))), 0)::real END ASC,
+  CASE WHEN sqlc.arg(order_by) = 'relevance'  AND sqlc.arg(order_dir) = 'desc'
+    THEN COALESCE(ts_rank(ads.search_vector, websearch_to_tsquery('russian', sqlc.narg(query)) || websearch_to_tsquery('english', sqlc.narg(query))), 0)::real END DESC,
+  CASE WHEN sqlc.arg(order_dir) = 'asc'  THEN ads.id END ASC,
+  CASE WHEN sqlc.arg(order_dir) = 'desc' THEN ads.id END DESC
+LIMIT $1 OFFSET $2;
+
+-- name: GetAdvertisementByID :one
+SELECT 
+  ads.id, 
+  ads.title, 
+  ads.description, 
+  ads.image_address, 
+  ads.image_id, 
+  ads.price, 
+  ads.status, 
+  ads.category_id, 
+  ads.created_at, 
+  ads.updated_at, 
+  ads.user_id, 
+  ads.image_broken, 
+  users.login AS author_login
+FROM advertisements AS ads
+JOIN users ON users.id = ads.user_id
+WHERE ads.id = $1;
+
+-- name: UpdateAdvertisement :one
+UPDATE advertisements
+SET 
+  title = $2,
+  description = $3,
+  image_address = $4,
+  price = $5,
+  category_id = $6,
+  updated_at = $7,
+  image_id = $8
+WHERE id = $1 AND updated_at = sqlc.arg(expected_updated_at)
+RETURNING *;
+
+-- name: DeleteAdvertisement :exec
+DELETE FROM advertisements
+WHERE id = $1;
+
+-- name: UpdateAdvertisementStatus :one
+UPDATE advertisements
+SET 
+  status = $2,
+  updated_at = $3
+WHERE id = $1 AND status = sqlc.arg(expected_status)
+RETURNING *;
+
+-- name: CountAdvertisements :one
+SELECT COUNT(*)
+FROM advertisements AS ads
+WHERE 
+  (sqlc.arg(min_price)::int IS NULL OR ads.price >= sqlc.arg(min_price))
+  AND (sqlc.arg(max_price)::int IS NULL OR ads.price <= sqlc.arg(max_price))
+  AND ads.status = sqlc.arg(status)
+  AND (sqlc.narg(user_id)::uuid IS NULL OR ads.user_id = sqlc.narg(user_id))
+  -- ads with a dead image are hidden from everyone but their author
+  AND (sqlc.arg(include_broken)::boolean OR NOT ads.image_broken)
+  AND (
+    sqlc.narg(query)::text IS NULL
+    OR ads.search_vector @@ (websearch_to_tsquery('russian', sqlc.narg(query)) || websearch_to_tsquery('english', sqlc.narg(query)))
+  )
+  AND (
+    sqlc.narg(category_id)::int IS NULL
+    OR ads.category_id IN (
+      WITH RECURSIVE subcategories AS (
+        SELECT id FROM categories WHERE id = sqlc.narg(category_id)
+        UNION ALL
+        SELECT categories.id FROM categories
+        JOIN subcategories ON categories.parent_id = subcategories.id
+      )
+      SELECT id FROM subcategories
+    )
+  );
+
+-- name: UpdateAdvertisementCover :exec
+UPDATE advertisements
+SET 
+  image_address = $2,
+  image_id = $3,
+  updated_at = $4
+WHERE id = $1;
+
+-- name: GetAdsForImageCheck :many
+SELECT id, image_address
+FROM advertisements
+WHERE 
+  status = 'published'
+  AND image_id IS NULL
+  AND (image_checked_at IS NULL OR image_checked_at < $1)
+ORDER BY image_checked_at NULLS FIRST
+LIMIT $2;
+
+-- name: RecordImageCheckSuccess :exec
+UPDATE advertisements
+SET 
+  image_checked_at = $2,
+  image_check_error = NULL,
+  image_check_failures = 0,
+  image_failing_since = NULL,
+  image_broken = false
+WHERE id = $1;
+
+-- name: RecordImageCheckFailure :exec
+UPDATE advertisements
+SET 
+  image_checked_at = $2,
+  image_check_error = $3,
+  image_check_failures = image_check_failures + 1,
+  image_failing_since = COALESCE(image_failing_since, $2),
+  image_broken = COALESCE(image_failing_since, $2) <= sqlc.arg(broken_since)::timestamp
+WHERE id = $1;
+
+-- name: ResetImageCheck :exec
+UPDATE advertisements
+SET 
+  image_checked_at = NULL,
+  image_check_error = NULL,
+  image_check_failures = 0,
+  image_failing_since = NULL,
+  image_broken = false
+WHERE id = $1;
+
+-- name: ArchiveUserAdvertisements :exec
+UPDATE advertisements
+SET 
+  status = 'archived',
+  updated_at = $2
+WHERE user_id = $1 AND status <> 'archived';
+
+-- name: GetUserAdvertisements :many
+SELECT * FROM advertisements
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,3 @@
+-- name: GetCategories :many
+SELECT * FROM categories
+ORDER BY parent_id NULLS FIRST, name;
@@ -0,0 +1,72 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports(id, user_id, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1;
+
+-- name: GetUnfinishedUserDataExport :one
+SELECT * FROM data_exports
+WHERE user_id = $1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetUserDataExports :many
+SELECT * FROM data_exports
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ClaimDataExport :one
+UPDATE data_exports
+SET 
+  status = 'processing',
+  started_at = sqlc.arg(started_at)
+WHERE id = (
+  SELECT id FROM data_exports
+  WHERE status = 'pending'
+    OR (status = 'processing' AND data_exports.started_at < sqlc.arg(stale_before)::timestamp)
+  ORDER BY created_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET 
+  status = 'ready',
+  storage_key = $2,
+  size = $3,
+  completed_at = $4,
+  expires_at = $5
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET 
+  status = 'failed',
+  error = $2,
+  completed_at = $3,
+  expires_at = $4
+WHERE id = $1;
+
+-- name: GetExpiredDataExports :many
+SELECT * FROM data_exports
+WHERE expires_at < $1
+ORDER BY expires_at
+LIMIT $2;
+
+-- name: DeleteDataExport :exec
+DELETE FROM data_exports
+WHERE id = $1;
+
+-- name: ExpireUserDataExports :exec
+UPDATE data_exports
+SET expires_at = $2
+WHERE user_id = $1 AND expires_at > $2;
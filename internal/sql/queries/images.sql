@@ -0,0 +1,25 @@
+-- name: CreateImage :one
+INSERT INTO images(id, user_id, storage_key, content_type, size, created_at, variant_widths)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: GetImageByID :one
+SELECT * FROM images
+WHERE id = $1;
+
+-- name: GetPublicImageByID :one
+SELECT images.* FROM images
+JOIN users ON users.id = images.user_id
+WHERE images.id = $1 AND users.deleted_at IS NULL;
+
+-- name: GetUserImages :many
+SELECT * FROM images
+WHERE user_id = $1;
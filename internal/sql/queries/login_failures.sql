@@ -0,0 +1,33 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures
+WHERE key = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures(key, failures, last_failure_at)
+VALUES ($1, 1, $2)
+ON CONFLICT (key) DO UPDATE
+SET
+  failures = CASE
+    WHEN login_failures.last_failure_at < sqlc.arg(reset_before)::timestamp THEN 1
+    ELSE login_failures.failures + 1
+  END,
+  last_failure_at = EXCLUDED.last_failure_at
+RETURNING *;
+
+-- name: ReleaseLoginFailure :exec
+UPDATE login_failures
+SET
+  failures = GREATEST(failures - 1, 0),
+  last_failure_at = CASE
+    WHEN last_failure_at = sqlc.arg(failed_at)::timestamp THEN sqlc.arg(previous_failure_at)::timestamp
+    ELSE last_failure_at
+  END
+WHERE key = $1;
+
+-- name: DeleteLoginFailure :exec
+DELETE FROM login_failures
+WHERE key = $1;
+
+-- name: DeleteStaleLoginFailures :exec
+DELETE FROM login_failures
+WHERE last_failure_at < $1;
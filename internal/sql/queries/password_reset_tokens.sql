@@ -0,0 +1,32 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens(id, user_id, token_hash, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetPasswordResetTokenByHash :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = $1;
+
+-- name: MarkPasswordResetTokenUsed :execrows
+UPDATE password_reset_tokens
+SET used_at = $2
+WHERE id = $1 AND used_at IS NULL;
+
+-- name: InvalidateUserPasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = $2
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteExpiredPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE expires_at < $1;
+
+-- name: GetUserPasswordResetTokens :many
+SELECT * FROM password_reset_tokens
+WHERE user_id = $1
+ORDER BY created_at;
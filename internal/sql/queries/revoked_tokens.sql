@@ -0,0 +1,19 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens(jti, user_id, expires_at, revoked_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS(
+    SELECT 1 FROM revoked_tokens
+    WHERE jti = $1
+);
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expires_at < $1;
+
+-- name: GetUserRevokedTokens :many
+SELECT * FROM revoked_tokens
+WHERE user_id = $1
+ORDER BY revoked_at;
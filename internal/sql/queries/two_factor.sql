@@ -0,0 +1,52 @@
+-- name: CreateUserTOTP :execrows
+INSERT INTO user_totp(user_id, secret, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET
+  secret = EXCLUDED.secret,
+  created_at = EXCLUDED.created_at,
+  last_used_step = 0
+WHERE user_totp.confirmed_at IS NULL;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: ConfirmUserTOTP :execrows
+UPDATE user_totp
+SET 
+  confirmed_at = $2,
+  last_used_step = $3
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes(id, user_id, code_hash, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = $3
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: GetUserRecoveryCodes :many
+SELECT * FROM recovery_codes
+WHERE user_id = $1
+ORDER BY created_at;
@@ -11,4 +11,79 @@ RETURNING *;
 
 -- name: GetUserByLogin :one
 SELECT * FROM users
-WHERE login = $1;
\ No newline at end of file
+WHERE login = $1;
+
+-- name: GetUserTokensValidAfter :one
+SELECT tokens_valid_after FROM users
+WHERE id = $1;
+
+-- name: UpdateUserTokensValidAfter :exec
+UPDATE users
+SET tokens_valid_after = $2
+WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET 
+  role = $2,
+  updated_at = $3
+WHERE login = $1
+RETURNING *;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1;
+
+-- name: LockUsersWithRole :many
+SELECT id FROM users
+WHERE role = $1
+FOR UPDATE;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET 
+  hashed_password = $2,
+  updated_at = $3
+WHERE id = $1;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET 
+  display_name = $2,
+  avatar_image_id = $3,
+  bio = $4,
+  city = $5,
+  contact_preferences = $6,
+  updated_at = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: AnonymizeUser :exec
+UPDATE users
+SET 
+  login = $2,
+  hashed_password = '',
+  role = 'user',
+  display_name = NULL,
+  avatar_image_id = NULL,
+  bio = NULL,
+  city = NULL,
+  contact_preferences = '{}',
+  tokens_valid_after = $3,
+  deleted_at = $4,
+  updated_at = $5
+WHERE id = $1;
+
+-- name: GetUsersToPurge :many
+SELECT id FROM users
+WHERE deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE advertisements
+ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+CHECK (status IN ('draft', 'published', 'reserved', 'sold', 'archived'));
+
+CREATE INDEX advertisements_status_idx ON advertisements(status);
+
+-- +goose Down
+DROP INDEX advertisements_status_idx;
+ALTER TABLE advertisements DROP COLUMN status;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE advertisements
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', title), 'A') ||
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('russian', description), 'B') ||
+    setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX advertisements_search_vector_idx ON advertisements USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX advertisements_search_vector_idx;
+ALTER TABLE advertisements DROP COLUMN search_vector;
@@ -0,0 +1,63 @@
+-- +goose Up
+CREATE TABLE categories(
+    id SERIAL PRIMARY KEY,
+    parent_id INT REFERENCES categories(id) ON DELETE RESTRICT,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE
+);
+
+CREATE INDEX categories_parent_id_idx ON categories(parent_id);
+
+INSERT INTO categories(name, slug) VALUES
+    ('Электроника', 'electronics'),
+    ('Транспорт', 'transport'),
+    ('Недвижимость', 'real-estate'),
+    ('Личные вещи', 'personal'),
+    ('Для дома и дачи', 'home'),
+    ('Хобби и отдых', 'hobby'),
+    ('Другое', 'other');
+
+INSERT INTO categories(parent_id, name, slug)
+SELECT parent.id, child.name, child.slug
+FROM (VALUES
+    ('electronics', 'Телефоны', 'phones'),
+    ('electronics', 'Ноутбуки', 'laptops'),
+    ('electronics', 'Планшеты', 'tablets'),
+    ('electronics', 'Аудио и видео', 'audio-video'),
+    ('electronics', 'Игровые приставки', 'consoles'),
+    ('transport', 'Автомобили', 'cars'),
+    ('transport', 'Мотоциклы', 'motorcycles'),
+    ('transport', 'Велосипеды', 'bicycles'),
+    ('transport', 'Запчасти', 'spare-parts'),
+    ('real-estate', 'Квартиры', 'apartments'),
+    ('real-estate', 'Дома', 'houses'),
+    ('personal', 'Одежда и обувь', 'clothes'),
+    ('personal', 'Часы и украшения', 'jewelry'),
+    ('home', 'Мебель', 'furniture'),
+    ('home', 'Бытовая техника', 'appliances'),
+    ('hobby', 'Спорт', 'sport'),
+    ('hobby', 'Книги', 'books'),
+    ('hobby', 'Музыкальные инструменты', 'music')
+) AS child(parent_slug, name, slug)
+JOIN categories AS parent ON parent.slug = child.parent_slug;
+
+INSERT INTO categories(parent_id, name, slug)
+SELECT parent.id, child.name, child.slug
+FROM (VALUES
+    ('phones', 'Смартфоны', 'smartphones'),
+    ('phones', 'Аксессуары для телефонов', 'phone-accessories'),
+    ('cars', 'Легковые', 'passenger-cars'),
+    ('cars', 'Грузовые', 'trucks')
+) AS child(parent_slug, name, slug)
+JOIN categories AS parent ON parent.slug = child.parent_slug;
+
+-- existing ads had no category at all, so they end up in "other"
+ALTER TABLE advertisements ADD COLUMN category_id INT REFERENCES categories(id) ON DELETE RESTRICT;
+UPDATE advertisements SET category_id = (SELECT id FROM categories WHERE slug = 'other');
+ALTER TABLE advertisements ALTER COLUMN category_id SET NOT NULL;
+
+CREATE INDEX advertisements_category_id_idx ON advertisements(category_id);
+
+-- +goose Down
+ALTER TABLE advertisements DROP COLUMN category_id;
+DROP TABLE categories;
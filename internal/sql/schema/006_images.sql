@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE images(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type TEXT NOT NULL,
+    size INT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE advertisements ADD COLUMN image_id UUID REFERENCES images(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE advertisements DROP COLUMN image_id;
+DROP TABLE images;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE ad_images(
+    id UUID PRIMARY KEY,
+    ad_id UUID NOT NULL REFERENCES advertisements(id) ON DELETE CASCADE,
+    image_address TEXT NOT NULL,
+    image_id UUID REFERENCES images(id) ON DELETE SET NULL,
+    position INT NOT NULL,
+    is_cover BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (ad_id, position)
+);
+
+CREATE UNIQUE INDEX ad_images_cover_idx ON ad_images(ad_id) WHERE is_cover;
+
+-- advertisements.image_address/image_id are kept as a copy of the cover image,
+-- so the feed doesn't have to join the gallery
+INSERT INTO ad_images(id, ad_id, image_address, image_id, position, is_cover, created_at)
+SELECT gen_random_uuid(), id, image_address, image_id, 0, true, created_at
+FROM advertisements;
+
+-- +goose Down
+DROP TABLE ad_images;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- widths of the resized copies stored next to the original, empty for images uploaded before
+ALTER TABLE images ADD COLUMN variant_widths INT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE images DROP COLUMN variant_widths;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- results of periodic re-validation of remote ad images
+ALTER TABLE advertisements
+    ADD COLUMN image_checked_at TIMESTAMP,
+    ADD COLUMN image_check_error TEXT,
+    ADD COLUMN image_check_failures INT NOT NULL DEFAULT 0,
+    ADD COLUMN image_failing_since TIMESTAMP,
+    ADD COLUMN image_broken BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX advertisements_image_checked_at_idx ON advertisements(image_checked_at NULLS FIRST)
+    WHERE status = 'published' AND image_id IS NULL;
+
+-- +goose Down
+DROP INDEX advertisements_image_checked_at_idx;
+ALTER TABLE advertisements
+    DROP COLUMN image_broken,
+    DROP COLUMN image_failing_since,
+    DROP COLUMN image_check_failures,
+    DROP COLUMN image_check_error,
+    DROP COLUMN image_checked_at;
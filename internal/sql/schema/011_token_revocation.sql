@@ -0,0 +1,17 @@
+-- +goose Up
+-- access tokens revoked before their expiry, rows are useless once the token expires
+CREATE TABLE revoked_tokens(
+    jti TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens(expires_at);
+
+-- tokens issued before this moment are rejected ("log out everywhere")
+ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN tokens_valid_after;
+DROP TABLE revoked_tokens;
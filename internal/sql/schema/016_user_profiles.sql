@@ -0,0 +1,16 @@
+-- +goose Up
+-- the avatar is an uploaded image, contact preferences are a JSON object described by dto.ContactPreferences
+ALTER TABLE users
+    ADD COLUMN display_name TEXT,
+    ADD COLUMN avatar_image_id UUID REFERENCES images(id) ON DELETE SET NULL,
+    ADD COLUMN bio TEXT,
+    ADD COLUMN city TEXT,
+    ADD COLUMN contact_preferences JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN contact_preferences,
+    DROP COLUMN city,
+    DROP COLUMN bio,
+    DROP COLUMN avatar_image_id,
+    DROP COLUMN display_name;
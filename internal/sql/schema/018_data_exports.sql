@@ -0,0 +1,24 @@
+-- +goose Up
+-- archives with everything stored about a user, built in the background
+CREATE TABLE data_exports(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    storage_key TEXT,
+    size BIGINT,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports(user_id);
+-- one export at a time per user
+CREATE UNIQUE INDEX data_exports_unfinished_idx ON data_exports(user_id) WHERE status IN ('pending', 'processing');
+CREATE INDEX data_exports_status_idx ON data_exports(status, created_at);
+CREATE INDEX data_exports_expires_at_idx ON data_exports(expires_at);
+
+-- +goose Down
+DROP TABLE data_exports;
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create storage: %v", err)
+	}
+
+	if err := store.Put(ctx, "images/photo.jpg", strings.NewReader("jpeg bytes")); err != nil {
+		t.Fatalf("couldn't put object: %v", err)
+	}
+
+	reader, err := store.Get(ctx, "images/photo.jpg")
+	if err != nil {
+		t.Fatalf("couldn't get object: %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "jpeg bytes" {
+		t.Fatalf("expected: %q, got: %q", "jpeg bytes", content)
+	}
+
+	if err := store.Delete(ctx, "images/photo.jpg"); err != nil {
+		t.Fatalf("couldn't delete object: %v", err)
+	}
+	if _, err := store.Get(ctx, "images/photo.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected: %v, got: %v", ErrNotFound, err)
+	}
+}
+
+func TestLocalStorageInvalidKeys(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create storage: %v", err)
+	}
+
+	tests := map[string]string{
+		"empty_key":        "",
+		"absolute_key":     "/etc/passwd",
+		"parent_traversal": "../secret",
+		"nested_traversal": "images/../../secret",
+	}
+
+	for name, key := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := store.Put(context.Background(), key, strings.NewReader("data"))
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Fatalf("%s: expected: %v, got: %v", name, ErrInvalidKey, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrMalformedImage = errors.New("malformed image")
+
+// StripMetadata removes EXIF, XMP, IPTC and comments from the original image without
+// re-encoding it, so the pixels stay exactly as uploaded. JPEG orientation is the only
+// tag kept: without it rotated photos would be displayed sideways.
+func StripMetadata(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/gif":
+		return stripGIF(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// stripJPEG keeps only the segments needed to decode the image: JFIF header, ICC color
+// profile and Adobe color transform among the application ones. Anything after the end
+// of the image is dropped as well.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrMalformedImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	// EXIF goes right after the JFIF header if there is one
+	var exif []byte
+	if orientation := jpegOrientation(data); orientation != 1 {
+		exif = orientationSegment(orientation)
+	}
+
+	pos := 2
+	for pos < len(data) {
+		if pos+2 > len(data) || data[pos] != 0xFF {
+			return nil, ErrMalformedImage
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			// fill byte before the actual marker
+			pos++
+			continue
+		case marker == 0xD9:
+			return append(out, 0xFF, 0xD9), nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// markers without payload
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+
+		if pos+4 > len(data) {
+			return nil, ErrMalformedImage
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrMalformedImage
+		}
+		segment := data[pos+4 : end]
+		if keepJPEGSegment(marker, segment) {
+			if exif != nil && marker != 0xE0 {
+				out = append(out, exif...)
+				exif = nil
+			}
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+
+		if marker == 0xDA {
+			// compressed data runs up to the next marker, 0xFF inside it is followed by 0x00
+			scanEnd := entropyDataEnd(data, pos)
+			out = append(out, data[pos:scanEnd]...)
+			pos = scanEnd
+		}
+	}
+	// images without the end marker still decode, so they are kept as they are
+	return out, nil
+}
+
+func keepJPEGSegment(marker byte, segment []byte) bool {
+	switch {
+	case marker == 0xFE:
+		// comment
+		return false
+	case marker == 0xE0:
+		return bytes.HasPrefix(segment, []byte("JFIF\x00")) || bytes.HasPrefix(segment, []byte("JFXX\x00"))
+	case marker == 0xE2:
+		return bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00"))
+	case marker == 0xEE:
+		return bytes.HasPrefix(segment, []byte("Adobe"))
+	case marker >= 0xE1 && marker <= 0xEF:
+		// EXIF, XMP, IPTC and vendor data
+		return false
+	}
+	return true
+}
+
+func entropyDataEnd(data []byte, pos int) int {
+	for pos+1 < len(data) {
+		if data[pos] != 0xFF {
+			pos++
+			continue
+		}
+		next := data[pos+1]
+		if next == 0x00 || (next >= 0xD0 && next <= 0xD7) {
+			pos += 2
+			continue
+		}
+		if next == 0xFF {
+			pos++
+			continue
+		}
+		return pos
+	}
+	return len(data)
+}
+
+// orientationSegment is an APP1 segment with EXIF data holding nothing but the orientation.
+func orientationSegment(orientation int) []byte {
+	// big-endian TIFF header and a single IFD entry, the value is stored in the entry itself
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1}
+	tiff = binary.BigEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(2+6+len(tiff)))
+	segment = append(segment, "Exif\x00\x00"...)
+	return append(segment, tiff...)
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are text, EXIF and modification time chunks, none of them affects the pixels.
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrMalformedImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	pos := len(pngSignature)
+	for pos < len(data) {
+		// length, type, data and crc
+		if pos+12 > len(data) {
+			return nil, ErrMalformedImage
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrMalformedImage
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			return out, nil
+		}
+	}
+	return nil, ErrMalformedImage
+}
+
+// stripGIF drops comments and application extensions, except the ones controlling animation loops.
+func stripGIF(data []byte) ([]byte, error) {
+	// header and logical screen descriptor
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, ErrMalformedImage
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+	if pos > len(data) {
+		return nil, ErrMalformedImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:pos]...)
+	for pos < len(data) {
+		start := pos
+		switch data[pos] {
+		case 0x3B:
+			// trailer
+			return append(out, 0x3B), nil
+		case 0x21:
+			if pos+2 > len(data) {
+				return nil, ErrMalformedImage
+			}
+			label := data[pos+1]
+			end, ok := gifSubBlocksEnd(data, pos+2)
+			if !ok {
+				return nil, ErrMalformedImage
+			}
+			if keepGIFExtension(label, data[pos+2:end]) {
+				out = append(out, data[start:end]...)
+			}
+			pos = end
+		case 0x2C:
+			// image descriptor, optional local color table, LZW code size and image data
+			if pos+10 > len(data) {
+				return nil, ErrMalformedImage
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			end, ok := gifSubBlocksEnd(data, pos+1)
+			if !ok {
+				return nil, ErrMalformedImage
+			}
+			out = append(out, data[start:end]...)
+			pos = end
+		default:
+			return nil, ErrMalformedImage
+		}
+	}
+	// images without the trailer still decode, so they are kept as they are
+	return out, nil
+}
+
+func keepGIFExtension(label byte, blocks []byte) bool {
+	switch label {
+	case 0xFE:
+		// comment
+		return false
+	case 0xFF:
+		// application identifier is the first sub-block, XMP is stored this way too
+		if len(blocks) < 12 || blocks[0] != 11 {
+			return false
+		}
+		identifier := string(blocks[1:12])
+		return identifier == "NETSCAPE2.0" || identifier == "ANIMEXTS1.0"
+	}
+	return true
+}
+
+// gifSubBlocksEnd returns the position right after the terminator of the sub-blocks starting at pos.
+func gifSubBlocksEnd(data []byte, pos int) (int, bool) {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, true
+		}
+		pos += size
+	}
+	return 0, false
+}
+
+// vp8x flags telling the EXIF and XMP chunks are present
+const (
+	webpExifFlag = 0x08
+	webpXMPFlag  = 0x04
+)
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformedImage
+	}
+
+	// anything after the RIFF container isn't part of the image
+	riffEnd := 8 + int(binary.LittleEndian.Uint32(data[4:]))
+	if riffEnd > len(data) {
+		return nil, ErrMalformedImage
+	}
+
+	out := make([]byte, 12, riffEnd)
+	copy(out, data[:12])
+	pos := 12
+	for pos < riffEnd {
+		if pos+8 > riffEnd {
+			return nil, ErrMalformedImage
+		}
+		chunkType := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		// chunks are padded to an even size
+		end := pos + 8 + size + size%2
+		if size < 0 || end > riffEnd {
+			return nil, ErrMalformedImage
+		}
+		switch chunkType {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunkStart := len(out)
+			out = append(out, data[pos:end]...)
+			if size > 0 {
+				out[chunkStart+8] &^= webpExifFlag | webpXMPFlag
+			}
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"testing"
+)
+
+func TestStripMetadata(t *testing.T) {
+	secret := []byte("GPS 55.7558 37.6173")
+
+	tests := map[string]struct {
+		data            []byte
+		contentType     string
+		wantOrientation int
+	}{
+		"jpeg": {
+			data:            jpegWithMetadata(t, 1, secret),
+			contentType:     "image/jpeg",
+			wantOrientation: 1,
+		},
+		"jpeg_rotated": {
+			data:            jpegWithMetadata(t, 6, secret),
+			contentType:     "image/jpeg",
+			wantOrientation: 6,
+		},
+		"png": {
+			data:        pngWithText(t, secret),
+			contentType: "image/png",
+		},
+		"gif": {
+			data:        gifWithComment(t, secret),
+			contentType: "image/gif",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := StripMetadata(tc.data, tc.contentType)
+			if err != nil {
+				t.Fatalf("%s: expected: %v, got: %v", name, nil, err)
+			}
+			if bytes.Contains(got, secret) {
+				t.Fatalf("%s: metadata is left in the image", name)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(got)); err != nil {
+				t.Fatalf("%s: stripped image doesn't decode: %v", name, err)
+			}
+			if tc.contentType == "image/jpeg" && jpegOrientation(got) != tc.wantOrientation {
+				t.Fatalf("%s: expected orientation: %d, got: %d", name, tc.wantOrientation, jpegOrientation(got))
+			}
+		})
+	}
+}
+
+func TestStripMetadataWebP(t *testing.T) {
+	secret := []byte("GPS 55.7558 37.6173")
+	// the image chunk isn't decoded, only the container is rewritten
+	data := riff(
+		webpChunk("VP8X", []byte{webpExifFlag | webpXMPFlag | 0x10, 0, 0, 0, 9, 0, 0, 9, 0, 0}),
+		webpChunk("VP8L", []byte{0x2F, 0x01, 0x02}),
+		webpChunk("EXIF", secret),
+		webpChunk("XMP ", secret),
+	)
+
+	got, err := StripMetadata(data, "image/webp")
+	if err != nil {
+		t.Fatalf("expected: %v, got: %v", nil, err)
+	}
+	want := riff(
+		webpChunk("VP8X", []byte{0x10, 0, 0, 0, 9, 0, 0, 9, 0, 0}),
+		webpChunk("VP8L", []byte{0x2F, 0x01, 0x02}),
+	)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("expected: %x, got: %x", want, got)
+	}
+}
+
+func TestStripMetadataMalformed(t *testing.T) {
+	jpegData := encodeJPEG(t, 10, 10, 1)
+	pngData := encodePNG(t, 10, 10)
+
+	tests := map[string]struct {
+		data        []byte
+		contentType string
+		wantErr     error
+	}{
+		"not_a_jpeg":       {data: pngData, contentType: "image/jpeg", wantErr: ErrMalformedImage},
+		"truncated_jpeg":   {data: jpegData[:20], contentType: "image/jpeg", wantErr: ErrMalformedImage},
+		"not_a_png":        {data: jpegData, contentType: "image/png", wantErr: ErrMalformedImage},
+		"png_without_iend": {data: pngData[:len(pngData)-12], contentType: "image/png", wantErr: ErrMalformedImage},
+		"not_a_gif":        {data: pngData, contentType: "image/gif", wantErr: ErrMalformedImage},
+		"not_a_webp":       {data: pngData, contentType: "image/webp", wantErr: ErrMalformedImage},
+		"unknown_format":   {data: pngData, contentType: "image/bmp", wantErr: ErrUnsupportedFormat},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := StripMetadata(tc.data, tc.contentType)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// jpegWithMetadata adds XMP and a comment holding secret, as well as trailing data after the image.
+func jpegWithMetadata(t *testing.T, orientation int, secret []byte) []byte {
+	t.Helper()
+	data := encodeJPEG(t, 10, 10, orientation)
+
+	var metadata []byte
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), secret...)
+	metadata = append(metadata, 0xFF, 0xE1)
+	metadata = binary.BigEndian.AppendUint16(metadata, uint16(len(xmp)+2))
+	metadata = append(metadata, xmp...)
+	metadata = append(metadata, 0xFF, 0xFE)
+	metadata = binary.BigEndian.AppendUint16(metadata, uint16(len(secret)+2))
+	metadata = append(metadata, secret...)
+
+	result := append(append(append([]byte{}, data[:2]...), metadata...), data[2:]...)
+	return append(result, secret...)
+}
+
+func pngWithText(t *testing.T, secret []byte) []byte {
+	t.Helper()
+	data := encodePNG(t, 10, 10)
+
+	// text chunk goes right after IHDR: signature, then 4+4+13+4 bytes of the header chunk
+	chunk := append([]byte("tEXt"), append([]byte("Comment\x00"), secret...)...)
+	text := binary.BigEndian.AppendUint32(nil, uint32(len(chunk)-4))
+	text = append(text, chunk...)
+	text = binary.BigEndian.AppendUint32(text, crc32.ChecksumIEEE(chunk))
+
+	headerEnd := 8 + 25
+	return append(append(append([]byte{}, data[:headerEnd]...), text...), data[headerEnd:]...)
+}
+
+func gifWithComment(t *testing.T, secret []byte) []byte {
+	t.Helper()
+	data := encodeGIF(t, 10, 10)
+
+	comment := []byte{0x21, 0xFE, byte(len(secret))}
+	comment = append(comment, secret...)
+	comment = append(comment, 0)
+	xmp := []byte{0x21, 0xFF, 11}
+	xmp = append(xmp, "XMP DataXMP"...)
+	xmp = append(xmp, byte(len(secret)))
+	xmp = append(xmp, secret...)
+	xmp = append(xmp, 0)
+
+	// extensions go before the trailer
+	result := append([]byte{}, data[:len(data)-1]...)
+	result = append(append(result, comment...), xmp...)
+	return append(result, 0x3B)
+}
+
+func riff(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	data := []byte("RIFF")
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(body)))
+	return append(data, body...)
+}
+
+func webpChunk(chunkType string, payload []byte) []byte {
+	chunk := []byte(chunkType)
+	chunk = binary.LittleEndian.AppendUint32(chunk, uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
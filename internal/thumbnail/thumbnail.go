@@ -0,0 +1,164 @@
+// Package thumbnail makes resized copies of uploaded images and strips metadata from the originals.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooManyPixels     = errors.New("image resolution is too big")
+)
+
+// DefaultWidths are the widths of variants generated for every uploaded image.
+var DefaultWidths = []int{200, 600, 1200}
+
+// decoding allocates 4 bytes per pixel, so huge images are rejected before that
+const maxPixels = 40_000_000
+
+const jpegQuality = 85
+
+// variantContentTypes maps content type of the original to the one of its variants.
+// There is no pure-Go webp encoder and animation makes no sense in a thumbnail,
+// so webp variants are jpeg and gif ones are png.
+var variantContentTypes = map[string]string{
+	"image/jpeg": "image/jpeg",
+	"image/png":  "image/png",
+	"image/gif":  "image/png",
+	"image/webp": "image/jpeg",
+}
+
+// VariantContentType returns the content type of variants made from an image of the given type.
+func VariantContentType(contentType string) string {
+	return variantContentTypes[contentType]
+}
+
+// Variant is a resized copy of the image.
+type Variant struct {
+	Width       int
+	ContentType string
+	Data        []byte
+}
+
+// Generate returns a variant for each of widths that is narrower than the image itself,
+// images are never upscaled. EXIF orientation of JPEG images is applied to the pixels
+// and all metadata is dropped since variants are encoded from scratch.
+// Only the first frame of animated gif is used.
+func Generate(data []byte, widths []int) ([]Variant, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	contentType, ok := variantContentTypes["image/"+format]
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// width of the image as it's displayed, rotated images have their sides swapped
+	displayWidth, displayHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	if orientation >= 5 {
+		displayWidth, displayHeight = displayHeight, displayWidth
+	}
+
+	var variants []Variant
+	for _, width := range widths {
+		if width >= displayWidth {
+			continue
+		}
+		height := max(1, displayHeight*width/displayWidth)
+
+		// resize first and rotate the small copy, it's much cheaper than the other way around
+		resizeWidth, resizeHeight := width, height
+		if orientation >= 5 {
+			resizeWidth, resizeHeight = height, width
+		}
+		resized := resize(src, resizeWidth, resizeHeight, contentType)
+
+		var buf bytes.Buffer
+		if contentType == "image/png" {
+			err = png.Encode(&buf, orient(resized, orientation))
+		} else {
+			err = jpeg.Encode(&buf, orient(resized, orientation), &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, Variant{Width: width, ContentType: contentType, Data: buf.Bytes()})
+	}
+	return variants, nil
+}
+
+func resize(src image.Image, width, height int, contentType string) draw.Image {
+	if contentType == "image/png" {
+		// keep transparency of png images
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+		return dst
+	}
+
+	// jpeg has no transparency, so transparent parts of webp images become white instead of black
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+	return dst
+}
+
+// orient transforms the image so it looks the way EXIF orientation tag says it should.
+func orient(src draw.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dstX, dstY int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dstX, dstY = width-1-x, y
+			case 3: // rotated 180°
+				dstX, dstY = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dstX, dstY = x, height-1-y
+			case 5: // transposed
+				dstX, dstY = y, x
+			case 6: // rotated 90° clockwise
+				dstX, dstY = height-1-y, x
+			case 7: // transversed
+				dstX, dstY = height-1-y, width-1-x
+			case 8: // rotated 90° counterclockwise
+				dstX, dstY = y, width-1-x
+			}
+			dst.Set(dstX, dstY, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,172 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := map[string]struct {
+		data       []byte
+		wantWidths []int
+		wantSizes  []image.Point
+		wantFormat string
+		wantErr    bool
+	}{
+		"png_without_upscaling": {
+			data:       encodePNG(t, 800, 400),
+			wantWidths: []int{200, 600},
+			wantSizes:  []image.Point{{200, 100}, {600, 300}},
+			wantFormat: "png",
+		},
+		"jpeg": {
+			data:       encodeJPEG(t, 1600, 800, 1),
+			wantWidths: []int{200, 600, 1200},
+			wantSizes:  []image.Point{{200, 100}, {600, 300}, {1200, 600}},
+			wantFormat: "jpeg",
+		},
+		"rotated_jpeg": {
+			data:       encodeJPEG(t, 800, 400, 6),
+			wantWidths: []int{200},
+			wantSizes:  []image.Point{{200, 400}},
+			wantFormat: "jpeg",
+		},
+		"gif_to_png": {
+			data:       encodeGIF(t, 400, 200),
+			wantWidths: []int{200},
+			wantSizes:  []image.Point{{200, 100}},
+			wantFormat: "png",
+		},
+		"too_small": {
+			data:       encodePNG(t, 100, 100),
+			wantWidths: nil,
+		},
+		"not_an_image": {
+			data:    []byte("definitely not an image"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			variants, err := Generate(tc.data, DefaultWidths)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("%s: expected error: %v, got: %v", name, tc.wantErr, err)
+			}
+			if len(variants) != len(tc.wantWidths) {
+				t.Fatalf("%s: expected %d variants, got: %d", name, len(tc.wantWidths), len(variants))
+			}
+
+			for index, variant := range variants {
+				if variant.Width != tc.wantWidths[index] {
+					t.Fatalf("%s: expected width: %d, got: %d", name, tc.wantWidths[index], variant.Width)
+				}
+				config, format, err := image.DecodeConfig(bytes.NewReader(variant.Data))
+				if err != nil {
+					t.Fatalf("%s: variant can't be decoded: %v", name, err)
+				}
+				if format != tc.wantFormat {
+					t.Fatalf("%s: expected format: %s, got: %s", name, tc.wantFormat, format)
+				}
+				if got := (image.Point{config.Width, config.Height}); got != tc.wantSizes[index] {
+					t.Fatalf("%s: expected size: %v, got: %v", name, tc.wantSizes[index], got)
+				}
+				if jpegOrientation(variant.Data) != 1 {
+					t.Fatalf("%s: variant must not contain orientation metadata", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateTooManyPixels(t *testing.T) {
+	// only the header is read, so a fake one is enough
+	var buf bytes.Buffer
+	buf.Write([]byte("\x89PNG\r\n\x1a\n"))
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 10000)
+	binary.BigEndian.PutUint32(ihdr[4:], 10000)
+	ihdr[8], ihdr[9] = 8, 6
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+	if _, err := Generate(buf.Bytes(), DefaultWidths); !errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("expected: %v, got: %v", ErrTooManyPixels, err)
+	}
+}
+
+func TestJpegOrientation(t *testing.T) {
+	tests := map[string]struct {
+		data []byte
+		want int
+	}{
+		"no_exif":          {data: encodeJPEG(t, 10, 10, 1), want: 1},
+		"rotated":          {data: encodeJPEG(t, 10, 10, 6), want: 6},
+		"mirrored":         {data: encodeJPEG(t, 10, 10, 2), want: 2},
+		"invalid_value":    {data: encodeJPEG(t, 10, 10, 42), want: 1},
+		"not_a_jpeg":       {data: []byte("plain text"), want: 1},
+		"truncated_header": {data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}, want: 1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := jpegOrientation(tc.data)
+			if got != tc.want {
+				t.Fatalf("%s: expected: %d, got: %d", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeGIF(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, testImage(width, height), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(width, height)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG makes a jpeg with an EXIF segment holding the orientation, 1 means no EXIF at all.
+func encodeJPEG(t *testing.T, width, height, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(width, height), nil); err != nil {
+		t.Fatal(err)
+	}
+	if orientation == 1 {
+		return buf.Bytes()
+	}
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), orientationSegment(orientation)...), data[2:]...)
+}
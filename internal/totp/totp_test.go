@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHOTP(t *testing.T) {
+	// RFC 6238, appendix B, SHA1 column
+	key := []byte("12345678901234567890")
+
+	tests := map[string]struct {
+		unix int64
+		want string
+	}{
+		"59":          {unix: 59, want: "94287082"},
+		"1111111109":  {unix: 1111111109, want: "07081804"},
+		"1111111111":  {unix: 1111111111, want: "14050471"},
+		"1234567890":  {unix: 1234567890, want: "89005924"},
+		"2000000000":  {unix: 2000000000, want: "69279037"},
+		"20000000000": {unix: 20000000000, want: "65353130"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := hotp(key, Counter(time.Unix(tc.unix, 0)), 8); got != tc.want {
+				t.Fatalf("%s: expected: %s, got: %s", name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		code   string
+		at     time.Time
+		wantOK bool
+	}{
+		"current_step":  {code: code, at: now, wantOK: true},
+		"previous_step": {code: code, at: now.Add(Period), wantOK: true},
+		"next_step":     {code: code, at: now.Add(-Period), wantOK: true},
+		"too_late":      {code: code, at: now.Add(2 * Period), wantOK: false},
+		"wrong_code":    {code: "000000", at: now, wantOK: code == "000000"},
+		"wrong_length":  {code: code[:5], at: now, wantOK: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			counter, ok := Validate(secret, tc.code, tc.at)
+			if ok != tc.wantOK {
+				t.Fatalf("%s: expected: %v, got: %v", name, tc.wantOK, ok)
+			}
+			if ok && counter != Counter(now) {
+				t.Fatalf("%s: expected: %d, got: %d", name, Counter(now), counter)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Marketplace", "alice", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Marketplace:alice" {
+		t.Fatalf("unexpected URI: %s", uri)
+	}
+	if got := uri.Query().Get("secret"); got != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("expected: %s, got: %s", "JBSWY3DPEHPK3PXP", got)
+	}
+}